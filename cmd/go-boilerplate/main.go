@@ -3,100 +3,380 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
+	"github.com/spf13/pflag"
 	"github.com/wasay-usmani/go-boilerplate/internal/go-boilerplate/app"
 	"github.com/wasay-usmani/go-boilerplate/internal/go-boilerplate/config"
+	appmigrations "github.com/wasay-usmani/go-boilerplate/internal/go-boilerplate/migrations/app"
+	schemamigrations "github.com/wasay-usmani/go-boilerplate/internal/go-boilerplate/migrations/schema"
 	http_server "github.com/wasay-usmani/go-boilerplate/internal/go-boilerplate/server/http"
 	"github.com/wasay-usmani/go-boilerplate/internal/go-boilerplate/server/rpc"
+	"github.com/wasay-usmani/go-boilerplate/pkg/configkit"
+	"github.com/wasay-usmani/go-boilerplate/pkg/dbkit"
+	"github.com/wasay-usmani/go-boilerplate/pkg/httpx"
+	"github.com/wasay-usmani/go-boilerplate/pkg/logkit"
+	"github.com/wasay-usmani/go-boilerplate/pkg/runkit"
 )
 
-const _shutdownTimeout = 5
+const usage = `Usage: %[1]s <command> [flags]
+
+Commands:
+  serve                 Start the HTTP and RPC servers (default)
+  migrate up            Apply all pending migrations, or up to --to
+  migrate down [n]      Roll back the last n app migrations (default 1), or down to --to
+  migrate redo          Roll back and re-apply the last app migration
+  migrate status        List applied and pending migrations
+  config                Print the effective config and validation report
+  version               Print build information
+
+Run '%[1]s <command> --help' for the command flags.
+`
 
 var build string // 0:8 GIT SHA injected at build time in Dockerfile
 
+var errInvalidConfig = errors.New("invalid config")
+
 func main() {
 	buildString := build
 	if buildString == "" {
 		buildString = "testing-unset"
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	// Without a command the service is started, so existing entrypoints keep working
+	cmd, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	var err error
+	switch cmd {
+	case "serve":
+		err = runServe(buildString, args)
+	case "migrate":
+		err = runMigrate(buildString, args)
+	case "config":
+		err = runConfig(buildString, args)
+	case "version":
+		printVersion(buildString)
+	case "help":
+		fmt.Printf(usage, os.Args[0])
+	default:
+		fmt.Fprintf(os.Stderr, usage, os.Args[0])
+		os.Exit(2)
+	}
+
+	if errors.Is(err, pflag.ErrHelp) {
+		return
+	}
 
-	// Load config
-	cfg, err := config.LoadConfig(buildString)
 	if err != nil {
-		log.Fatalln("load config error", err)
+		log.Fatalln(cmd, "error", err)
 	}
+}
 
-	// Initialize app module
-	appModule, appCleanUp := app.NewModule(cfg)
-
-	// Initialize requests handler
-	hBase := http_server.NewHandlerBase(cfg, appModule)
-	router := hBase.LoadRoutes()
-
-	// Start API Server
-	server := &http.Server{
-		Addr:         cfg.ListenHost + ":" + cfg.ListenPort,
-		Handler:      router,
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		IdleTimeout:  5 * time.Minute,
-	}
-
-	// Start RPC Server
-	rpcHandle := rpc.NewHandlerBase(cfg, appModule)
-
-	go func() {
-		// Start Serving Connections
-		rpcErr := rpcHandle.Run()
-		if rpcErr != nil {
-			log.Fatal(
-				"Server Error while trying to serve rpc",
-				"rpc.port", cfg.RPCListenPort,
-				"err", rpcErr,
-			)
-		}
-	}()
-
-	go func() {
-		// Start Serving Connections
-		httpErr := server.ListenAndServe()
-		if httpErr != nil && !errors.Is(httpErr, http.ErrServerClosed) {
-			log.Fatal(
-				"Server Error while trying to serve http",
-				"http.host", cfg.ListenHost,
-				"http.port", cfg.ListenPort,
-				"err", httpErr,
-			)
-		}
-	}()
+// runServe starts the HTTP and RPC servers, or only one of them with --http-only or --rpc-only.
+func runServe(buildString string, args []string) error {
+	fs, configFlags := newFlagSet("serve")
+	httpOnly := fs.Bool("http-only", false, "start only the HTTP server")
+	rpcOnly := fs.Bool("rpc-only", false, "start only the RPC server")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *httpOnly && *rpcOnly {
+		return errors.New("--http-only and --rpc-only are mutually exclusive")
+	}
 
 	// Listen for Shutdown Signal
 	// kill (no param) default send syscall.SIGTERM
 	// kill -2 is syscall.SIGINT
 	// kill -9 is syscall. SIGKILL but can't be caught, so no need to add it
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Load config
+	cfg, err := config.LoadConfig(buildString, configkit.WithFlags(configFlags))
+	if err != nil {
+		return fmt.Errorf("load config error: %w", err)
+	}
+
+	// Initialize logger
+	logger := logkit.NewLogger(logkit.LogLevel(cfg.Log.Level), config.AppName)
 
-	// Blocking op; waiting for signal
-	<-quit
+	// Reload runtime configuration on config file changes
+	watcher := watchConfig(cfg, logger)
 
-	// Shutdown HTTP Server
-	// Shutdown HTTP server
-	subCtx, shutdownCancel := context.WithTimeout(ctx, _shutdownTimeout*time.Second)
-	_ = server.Shutdown(subCtx)
+	// Initialize app module
+	appModule, appCleanUp, err := app.NewModule(ctx, cfg, logger)
+	if err != nil {
+		return fmt.Errorf("init app module error: %w", err)
+	}
+
+	// Serve until a shutdown signal or the first fatal server error,
+	// then drain the servers before releasing app resources
+	manager := runkit.NewManager(logger, cfg.ShutdownTimeout)
+	if !*rpcOnly {
+		// Initialize requests handler
+		hBase := http_server.NewHandlerBase(cfg, appModule)
+		router := hBase.LoadRoutes()
 
-	close(quit)
+		// API Server
+		server := &http.Server{
+			Addr:              cfg.HTTP.ListenHost + ":" + cfg.HTTP.ListenPort,
+			Handler:           router,
+			ReadTimeout:       cfg.HTTP.ReadTimeout,
+			ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+			WriteTimeout:      cfg.HTTP.WriteTimeout,
+			IdleTimeout:       cfg.HTTP.IdleTimeout,
+			MaxHeaderBytes:    cfg.HTTP.MaxHeaderBytes,
+		}
+
+		server.SetKeepAlivesEnabled(cfg.HTTP.EnableKeepAlive)
+		manager.AddServer("http", runkit.HTTPServer(server))
+	}
+
+	if !*httpOnly {
+		// RPC Server
+		manager.AddServer("rpc", rpc.NewHandlerBase(cfg, appModule))
+	}
+
+	// Metrics Server, on its own port so the metrics are not exposed with the API
+	if cfg.HTTP.MetricsListenPort != "" {
+		manager.AddServer("metrics", runkit.HTTPServer(newMetricsServer(cfg, appModule)))
+	}
+
+	// Outbox relay, publishing the events enqueued by the commands
+	if appModule.Outbox.HasPublisher() {
+		manager.AddWorker("outbox", appModule.Outbox.Run)
+	} else {
+		logger.Warn("outbox has no publisher, enqueued events are not relayed")
+	}
+
+	// Hard deletes the soft deleted rows past their retention
+	if appModule.Purger.Enabled() {
+		manager.AddWorker("purge", appModule.Purger.Run)
+	}
+
+	// Stops watching the config file on shutdown
+	if watcher != nil {
+		manager.AddWorker("config-watch", watcher.Run)
+	}
+
+	runErr := manager.Run(ctx)
 	appCleanUp()
 	// Uncomment when using zap logger
 	// _ = logger.Sync()
-	cancel()
-	shutdownCancel()
+
+	if runErr != nil {
+		return fmt.Errorf("server stopped with error: %w", runErr)
+	}
+
+	return nil
+}
+
+// runMigrate applies, rolls back or lists the database migrations.
+func runMigrate(buildString string, args []string) error {
+	fs, configFlags := newFlagSet("migrate <up|down [n]|redo|status>")
+	to := fs.String("to", "", "migrate up or down to this migration id, of the --set migrations")
+	set := fs.String("set", dbkit.AppMigrations, "migration set targeted by --to: schema or app")
+	dryRun := fs.Bool("dry-run", false, "print the SQL of the planned migrations instead of executing it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("missing migrate action")
+	}
+
+	// Only the DB section is required, migration jobs do not configure the servers
+	cfg, err := config.LoadDBConfig(buildString, configkit.WithFlags(configFlags))
+	if err != nil {
+		return fmt.Errorf("load config error: %w", err)
+	}
+
+	opts := []dbkit.MigratorOption{dbkit.WithConnOptions(app.DBPoolOptions(cfg.DB.Pool)...)}
+	if cfg.DB.Dialect != "" {
+		opts = append(opts, dbkit.WithDialect(dbkit.Dialect(cfg.DB.Dialect)))
+	}
+
+	if *dryRun {
+		opts = append(opts, dbkit.WithDryRun(os.Stdout))
+	}
+
+	logLevel := logkit.Info
+	if cfg.Log.Level != "" {
+		logLevel = logkit.LogLevel(cfg.Log.Level)
+	}
+
+	logger := logkit.NewLogger(logLevel, config.AppName)
+	migrator := dbkit.NewMigrator(cfg.DB.SuperUserDatabaseURL.Value(), cfg.DB.SQLWriteURL.Value(), cfg.DB.Schema, logger,
+		schemamigrations.Assets, appmigrations.Assets, opts...)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	switch action := fs.Arg(0); {
+	case action == "up" && *to != "":
+		return migrator.UpTo(ctx, *set, *to)
+	case action == "up":
+		return migrator.Up(ctx)
+	case action == "down" && *to != "":
+		return migrator.DownTo(ctx, *set, *to)
+	case action == "down":
+		steps := 1
+		if fs.NArg() > 1 {
+			if steps, err = strconv.Atoi(fs.Arg(1)); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of migrations to roll back: %s", fs.Arg(1))
+			}
+		}
+
+		return migrator.Down(ctx, steps)
+	case action == "redo":
+		return migrator.Redo(ctx)
+	case action == "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		return printMigrationStatus(statuses)
+	default:
+		return fmt.Errorf("unknown migrate action %q", action)
+	}
+}
+
+// runConfig prints the effective config with secrets redacted, the source of
+// each value and every validation issue. An invalid config is reported as an error.
+func runConfig(buildString string, args []string) error {
+	fs, configFlags := newFlagSet("config")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := config.Inspect(buildString, configkit.WithFlags(configFlags))
+	if err != nil {
+		return fmt.Errorf("inspect config error: %w", err)
+	}
+
+	if err := report.Write(os.Stdout); err != nil {
+		return fmt.Errorf("print config error: %w", err)
+	}
+
+	if !report.Valid() {
+		return errInvalidConfig
+	}
+
+	return nil
+}
+
+// printVersion prints the build SHA along with the Go and module versions.
+func printVersion(buildString string) {
+	fmt.Printf("%s %s\n", config.AppName, buildString)
+	fmt.Printf("go: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Path == "" {
+		return
+	}
+
+	fmt.Printf("module: %s %s\n", info.Main.Path, info.Main.Version)
+	for _, s := range info.Settings {
+		if strings.HasPrefix(s.Key, "vcs.") {
+			fmt.Printf("%s: %s\n", s.Key, s.Value)
+		}
+	}
+}
+
+func printMigrationStatus(statuses []dbkit.MigrationStatus) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SET\tMIGRATION\tAPPLIED AT")
+	for _, s := range statuses {
+		appliedAt := "pending"
+		if s.Applied {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.Set, s.ID, appliedAt)
+	}
+
+	return w.Flush()
+}
+
+// newFlagSet returns the command flag set and the subset of flags overriding config values.
+func newFlagSet(name string) (fs, configFlags *pflag.FlagSet) {
+	configFlags = pflag.NewFlagSet("config", pflag.ContinueOnError)
+	configFlags.String("log-level", "", "override LOG_LEVEL")
+	configFlags.String("http-listen-port", "", "override HTTP_LISTEN_PORT")
+	configFlags.String("rpc-listen-port", "", "override RPC_LISTEN_PORT")
+
+	fs = pflag.NewFlagSet(name, pflag.ContinueOnError)
+	fs.AddFlagSet(configFlags)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s [flags]\n", os.Args[0], name)
+		fs.PrintDefaults()
+	}
+
+	return fs, configFlags
+}
+
+// newMetricsServer serves the Prometheus metrics of the app module on httpx.MetricsPath.
+func newMetricsServer(cfg *config.Config, appModule *app.Module) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle(httpx.MetricsPath, appModule.QueryMetrics)
+
+	return &http.Server{
+		Addr:              cfg.HTTP.ListenHost + ":" + cfg.HTTP.MetricsListenPort,
+		Handler:           mux,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+	}
+}
+
+// watchConfig applies runtime config changes, such as LOG_LEVEL, without a restart.
+// Changes are validated against the full service config before being applied.
+// It returns nil when the config file cannot be watched.
+func watchConfig(cfg *config.Config, logger logkit.Logger) *configkit.Watcher {
+	watcher, err := cfg.Watch(logger)
+	if err != nil {
+		if !errors.Is(err, configkit.ErrNoConfigFile) {
+			logger.Error("failed to watch config", err)
+		}
+
+		return nil
+	}
+
+	watcher.AddValidator(func(next *configkit.C) error {
+		_, err := config.New(next)
+		return err
+	})
+
+	watcher.Subscribe(func(prev, next *configkit.C) {
+		if prev.Log.Level == next.Log.Level {
+			return
+		}
+
+		if err := logkit.SetLevel(logkit.LogLevel(next.Log.Level)); err != nil {
+			logger.Error("failed to change log level", err)
+			return
+		}
+
+		logger.Info("log level changed", "from", prev.Log.Level, "to", next.Log.Level)
+	})
+
+	return watcher
 }
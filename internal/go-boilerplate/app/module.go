@@ -1,27 +1,140 @@
 package app
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	goboilerplate "github.com/wasay-usmani/go-boilerplate/internal/go-boilerplate/app/go-boilerplate"
 	"github.com/wasay-usmani/go-boilerplate/internal/go-boilerplate/config"
 	"github.com/wasay-usmani/go-boilerplate/internal/go-boilerplate/repository"
+	"github.com/wasay-usmani/go-boilerplate/pkg/configkit"
+	"github.com/wasay-usmani/go-boilerplate/pkg/dbkit"
+	"github.com/wasay-usmani/go-boilerplate/pkg/logkit"
+	"go.opentelemetry.io/otel"
 )
 
 type Module struct {
 	Boilerplate goboilerplate.App
+
+	// QueryMetrics records the duration of every database query, served on httpx.MetricsPath
+	// of the HTTP_METRICS_LISTEN_PORT
+	QueryMetrics *dbkit.QueryHistogram
+
+	// Outbox holds the events enqueued by the commands until its relay publishes them
+	Outbox *dbkit.Outbox
+
+	// Purger hard deletes the soft deleted rows past their retention
+	Purger *dbkit.Purger
+
+	// Cursors signs and verifies the cursors of the keyset paginated lists
+	Cursors *dbkit.CursorCodec
 }
 
-func NewModule(cfg *config.Config) (module *Module, cleanup func()) {
-	// Initialize read & write database connections
-	// writeDB, readDB := initDBConns(cfg)
-	writeR := repository.NewModule()
-	readR := repository.NewModule()
+// NewModule connects the databases, waiting for them until ctx is done when connect
+// retries are configured, and wires the app.
+func NewModule(ctx context.Context, cfg *config.Config, l logkit.Logger) (module *Module, cleanup func(), err error) {
+	cursors, err := dbkit.NewCursorCodec([]byte(cfg.CursorKey.Value()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s: %w", config.CursorKeyKey, err)
+	}
+
+	// Initialize read & write database connections, timing every query and
+	// attaching it to the trace of the request, if any
+	queryMetrics := dbkit.NewQueryHistogram()
+	opts := append(DBPoolOptions(cfg.DB.Pool),
+		dbkit.WithSlowQueryThreshold(cfg.DBSlowQueryThreshold),
+		dbkit.WithQueryObserver(queryMetrics, dbkit.NewTraceObserver(otel.GetTracerProvider())),
+	)
+	writeDB, readDB, err := initDBConns(ctx, cfg, l, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Reads are routed to the read db and fall back to the write db when it is down,
+	// writes and transactions always go to the write db
+	db := dbkit.NewRouter(writeDB, []*dbkit.SQLConn{readDB}, l)
+
+	// Events wait in the outbox until a dbkit.Publisher, e.g. a message broker producer,
+	// is passed here, the relay only runs with one
+	outbox := dbkit.NewOutbox(db.Primary(), nil, l, dbkit.WithOutboxDialect(dbDialect(cfg)))
+	repo := repository.NewModule(db, outbox)
+
+	// Soft deleted tables are purged once listed here, children before the tables they reference
+	purger := dbkit.NewPurger(db.Primary(), l, nil,
+		dbkit.WithPurgeDialect(dbDialect(cfg)),
+		dbkit.WithPurgeRetention(time.Duration(cfg.DBSoftDeleteRetentionDays)*24*time.Hour),
+	)
+
 	return &Module{
-			Boilerplate: goboilerplate.New(writeR, readR),
-		}, func() {
-			// Database cleanup will be implemented when database connections are added
+		Boilerplate:  goboilerplate.New(repo, repo),
+		QueryMetrics: queryMetrics,
+		Outbox:       outbox,
+		Purger:       purger,
+		Cursors:      cursors,
+	}, func() {
+		if err := db.Close(); err != nil {
+			l.Error("failed to close db connections", err)
 		}
+	}, nil
 }
 
 func NewMockModule() *Module {
 	return &Module{}
 }
+
+// DBPoolOptions maps the DB_* pool settings to dbkit options. Only the set ones are
+// mapped, even when zero, the others keep the dbkit and database/sql defaults.
+func DBPoolOptions(pool configkit.DBPool) []dbkit.Option {
+	var opts []dbkit.Option
+	opts = appendOption(opts, pool.MaxOpenConns, dbkit.WithMaxOpenConns)
+	opts = appendOption(opts, pool.MaxIdleConns, dbkit.WithMaxIdleConns)
+	opts = appendOption(opts, pool.ConnMaxLifetime, dbkit.WithConnMaxLifetime)
+	opts = appendOption(opts, pool.ConnMaxIdleTime, dbkit.WithConnMaxIdleTime)
+	opts = appendOption(opts, pool.ConnectTimeout, dbkit.WithConnectTimeout)
+	opts = appendOption(opts, pool.ConnectRetries, dbkit.WithConnectRetries)
+	return appendOption(opts, pool.ConnectBackoff, dbkit.WithConnectBackoff)
+}
+
+func appendOption[T any](opts []dbkit.Option, value *T, option func(T) dbkit.Option) []dbkit.Option {
+	if value == nil {
+		return opts
+	}
+
+	return append(opts, option(*value))
+}
+
+// dbDialect returns DB_DIALECT or, when empty, the dialect of the write db URL.
+func dbDialect(cfg *config.Config) dbkit.Dialect {
+	if cfg.DB.Dialect != "" {
+		return dbkit.Dialect(cfg.DB.Dialect)
+	}
+
+	return dbkit.DialectFromURL(cfg.DB.SQLWriteURL.Value())
+}
+
+// initDBConns connects to the DB_SCHEMA of the write and read dbs, with the driver of
+// DB_DIALECT or, when empty, of their URLs.
+func initDBConns(
+	ctx context.Context, cfg *config.Config, l logkit.Logger, opts ...dbkit.Option,
+) (writeDB, readDB *dbkit.SQLConn, err error) {
+	dialect := dbkit.Dialect(cfg.DB.Dialect)
+	writeDB, err = dbkit.LoadSchemaConn(ctx, cfg.DB.SQLWriteURL.Value(), dialect, cfg.DB.Schema, cfg.Debug, l, opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load write db: %w", err)
+	}
+
+	readDB, err = dbkit.LoadSchemaConn(ctx, cfg.DB.SQLReadURL.Value(), dialect, cfg.DB.Schema, cfg.Debug, l, opts...)
+	if err != nil {
+		closeDBConn(writeDB, "write", l)
+		return nil, nil, fmt.Errorf("failed to load read db: %w", err)
+	}
+
+	return writeDB, readDB, nil
+}
+
+func closeDBConn(db *dbkit.SQLConn, name string, l logkit.Logger) {
+	if err := db.Close(); err != nil {
+		l.Error("failed to close db connection", err, "db", name)
+	}
+}
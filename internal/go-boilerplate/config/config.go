@@ -2,10 +2,10 @@ package config
 
 import (
 	"fmt"
-	"log"
+	"time"
 
 	"github.com/go-playground/validator/v10"
-	"github.com/spf13/viper"
+	"github.com/wasay-usmani/go-boilerplate/pkg/configkit"
 )
 
 const (
@@ -13,23 +13,30 @@ const (
 	GitRepo = "go-boilerplate"
 )
 
+// Service specific config keys, see configkit for the shared ones.
+const (
+	DBSlowQueryThresholdKey      = "DB_SLOW_QUERY_THRESHOLD"
+	DBSoftDeleteRetentionDaysKey = "DB_SOFT_DELETE_RETENTION_DAYS"
+	TenantHeaderKey              = "TENANT_HEADER"
+	CursorKeyKey                 = "CURSOR_KEY"
+)
+
+// Config embeds the shared configkit sections, e.g. the database settings in DB.
 type Config struct {
-	ListenHost    string `validate:"required"`
-	ListenPort    string `validate:"required"`
-	RPCListenPort string `validate:"required"`
-	LogLevel      string `validate:"required"`
-	Environment   string `validate:"required"`
-	Debug         bool
-
-	DBSchema             string `validate:"required"`
-	SuperUserDatabaseURL string `validate:"required"`
-	WriteDBURL           string `validate:"required"`
-	ReadDBURL            string `validate:"required"`
+	*configkit.C
+
+	// DBSlowQueryThreshold logs slower queries as warnings, 0 disables it
+	DBSlowQueryThreshold time.Duration `env:"DB_SLOW_QUERY_THRESHOLD" validate:"gte=0"`
+	// DBSoftDeleteRetentionDays is how long soft deleted rows are kept before being purged, 0 disables purging
+	DBSoftDeleteRetentionDays int `env:"DB_SOFT_DELETE_RETENTION_DAYS" validate:"gte=0"`
+	// TenantHeader carries the tenant of API requests, set by the gateway, empty for single tenant deployments
+	TenantHeader string `env:"TENANT_HEADER"`
+	// CursorKey signs the pagination cursors, shared by every replica of the service
+	CursorKey configkit.Secret `env:"CURSOR_KEY" validate:"required,min=32"`
 }
 
-// LoadConfig returns app configuration, recommend setting app build as
-// build arg for proper/expected log output
-// LoadConfig reads viper.Viper config using gitrepo & appName in the following paths
+// LoadConfig layers defaults, config file, .env, env vars and the given
+// flags through configkit.Load, searching the config file in the following paths
 //
 //	config
 //	/etc/#{appName}/config
@@ -38,34 +45,53 @@ type Config struct {
 //	../../config
 //	../../../../#{gitRepo}/internal/#{appName}/config
 //	../../../#{gitRepo}/internal/#{appName}/config
-func LoadConfig(appBuild string) (*Config, error) {
-	v := viper.New()
-	v.AutomaticEnv()
-	v.AddConfigPath("config")
-	v.AddConfigPath(fmt.Sprintf("/etc/%s/config", AppName))
-	v.AddConfigPath(fmt.Sprintf("./internal/%s/config", AppName))
-	v.AddConfigPath("../config")
-	v.AddConfigPath("../../config")
-	v.AddConfigPath(fmt.Sprintf("../../../../%s/internal/%s/config", GitRepo, AppName))
-	v.AddConfigPath(fmt.Sprintf("../../../%s/internal/%s/config", GitRepo, AppName))
-
-	v.SetConfigName("config")
-
-	if err := v.ReadInConfig(); err != nil {
-		log.Printf("Config file not found, using ENV vars. Note: This is expected behavior in k8s. Msg:	 %s\n", err)
+func LoadConfig(appBuild string, opts ...configkit.Option) (*Config, error) {
+	c, err := configkit.Load(appBuild, withServiceOptions(opts)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(c)
+}
+
+// LoadDBConfig loads the configuration like LoadConfig but only validates its DB
+// section, so commands only using the database, such as migrations, do not need
+// the settings of the servers.
+func LoadDBConfig(appBuild string, opts ...configkit.Option) (*configkit.C, error) {
+	c, err := configkit.Build(appBuild, withServiceOptions(opts)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.DB.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Inspect loads the configuration without failing on validation errors and
+// reports every effective value, its source and all validation issues.
+func Inspect(appBuild string, opts ...configkit.Option) (*configkit.Report, error) {
+	c, err := configkit.Build(appBuild, withServiceOptions(opts)...)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := build(c)
+	if err != nil {
+		return nil, err
 	}
 
-	// Determine Runtime Environment
-	config := Config{
-		Debug:                v.GetBool("DEBUG"),
-		LogLevel:             v.GetString("LOG_LEVEL"),
-		Environment:          v.GetString("ENVIRONMENT"),
-		ListenHost:           v.GetString("HTTP_LISTEN_HOST"),
-		ListenPort:           v.GetString("HTTP_LISTEN_PORT"),
-		DBSchema:             v.GetString("DB_SCHEMA"),
-		SuperUserDatabaseURL: v.GetString("SUPERUSER_DATABASE_URL"),
-		WriteDBURL:           v.GetString("WRITE_DB_URL"),
-		ReadDBURL:            v.GetString("READ_DB_URL"),
+	return configkit.NewReport(c, config), nil
+}
+
+// New builds the service configuration on top of an already loaded configkit.C.
+// It is also used to validate configuration reloads.
+func New(c *configkit.C) (*Config, error) {
+	config, err := build(c)
+	if err != nil {
+		return nil, err
 	}
 
 	// Validate Config
@@ -73,5 +99,35 @@ func LoadConfig(appBuild string) (*Config, error) {
 		return nil, fmt.Errorf("config validation failed: %w", err)
 	}
 
-	return &config, nil
+	return config, nil
+}
+
+func build(c *configkit.C) (*Config, error) {
+	cursorKey, err := c.GetSecret(CursorKeyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Config{
+		C:                         c,
+		DBSlowQueryThreshold:      c.Viper.GetDuration(DBSlowQueryThresholdKey),
+		DBSoftDeleteRetentionDays: c.Viper.GetInt(DBSoftDeleteRetentionDaysKey),
+		TenantHeader:              c.Viper.GetString(TenantHeaderKey),
+		CursorKey:                 cursorKey,
+	}, nil
+}
+
+func withServiceOptions(opts []configkit.Option) []configkit.Option {
+	return append([]configkit.Option{
+		configkit.WithAppName(AppName),
+		configkit.WithConfigPaths(
+			"config",
+			fmt.Sprintf("/etc/%s/config", AppName),
+			fmt.Sprintf("./internal/%s/config", AppName),
+			"../config",
+			"../../config",
+			fmt.Sprintf("../../../../%s/internal/%s/config", GitRepo, AppName),
+			fmt.Sprintf("../../../%s/internal/%s/config", GitRepo, AppName),
+		),
+	}, opts...)
 }
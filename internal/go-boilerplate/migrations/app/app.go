@@ -0,0 +1,9 @@
+package app
+
+import "embed"
+
+// Assets holds the app migrations, applied with the app connection. The migrations
+// of the mysql and postgres directories only run on their dialect.
+//
+//go:embed migrations/*.sql migrations/mysql/*.sql migrations/postgres/*.sql
+var Assets embed.FS
@@ -0,0 +1,4 @@
+-- +migrate Up
+-- App tables are created by the migrations that follow this one
+
+-- +migrate Down
@@ -0,0 +1,18 @@
+-- +migrate Up
+-- Events enqueued with dbkit.Outbox in the transaction of the write they describe,
+-- published by the outbox relay
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    topic VARCHAR(255) NOT NULL,
+    payload LONGBLOB NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NULL,
+    available_at DATETIME(6) NOT NULL,
+    created_at DATETIME(6) NOT NULL,
+    delivered_at DATETIME(6) NULL,
+    PRIMARY KEY (id),
+    INDEX outbox_pending_idx (delivered_at, available_at)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS outbox;
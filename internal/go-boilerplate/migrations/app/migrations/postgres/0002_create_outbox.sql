@@ -0,0 +1,19 @@
+-- +migrate Up
+-- Events enqueued with dbkit.Outbox in the transaction of the write they describe,
+-- published by the outbox relay
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL NOT NULL,
+    topic VARCHAR(255) NOT NULL,
+    payload BYTEA NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NULL,
+    available_at TIMESTAMPTZ(6) NOT NULL,
+    created_at TIMESTAMPTZ(6) NOT NULL,
+    delivered_at TIMESTAMPTZ(6) NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (delivered_at, available_at);
+
+-- +migrate Down
+DROP TABLE IF EXISTS outbox;
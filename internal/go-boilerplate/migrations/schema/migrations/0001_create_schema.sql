@@ -0,0 +1,6 @@
+-- +migrate Up
+-- The DB_SCHEMA database (MySQL) or schema (Postgres) is created by the migrator before
+-- any migration runs. Statements requiring the superuser, e.g. roles, grants or
+-- extensions, go in the migrations that follow this one
+
+-- +migrate Down
@@ -0,0 +1,8 @@
+package schema
+
+import "embed"
+
+// Assets holds the schema migrations, applied with the superuser connection
+//
+//go:embed migrations/*.sql
+var Assets embed.FS
@@ -1 +1,50 @@
+// Package convertors maps the entities of the service between their three shapes:
+// the domain types the app works with, the sqlboiler models stored through the
+// repository and the proto messages served over gRPC. Each entity has its own file,
+// e.g. user.go, with one function per direction:
+//
+//	func UserToModel(u *domain.User) *models.User
+//	func UserFromModel(m *models.User) *domain.User
+//	func UserToProto(u *domain.User) *pb.User
+//	func UserFromProto(p *pb.User) (*domain.User, error)
+//
+// Models and proto messages never convert into each other, they go through the
+// domain type. Convertors are pure: no I/O and no business rules, a nil input
+// gives a nil output. Only conversions that can fail, e.g. parsing ids or enums
+// sent by clients, return an error. Slices are converted with Slice and SliceErr:
+//
+//	users := convertors.Slice(rows, convertors.UserFromModel)
 package convertors
+
+// Slice converts every element of in with fn, a nil slice stays nil.
+func Slice[S ~[]E, E, T any](in S, fn func(E) T) []T {
+	if in == nil {
+		return nil
+	}
+
+	out := make([]T, len(in))
+	for i, e := range in {
+		out[i] = fn(e)
+	}
+
+	return out
+}
+
+// SliceErr converts every element of in with fn, stopping at the first error.
+func SliceErr[S ~[]E, E, T any](in S, fn func(E) (T, error)) ([]T, error) {
+	if in == nil {
+		return nil, nil
+	}
+
+	out := make([]T, len(in))
+	for i, e := range in {
+		t, err := fn(e)
+		if err != nil {
+			return nil, err
+		}
+
+		out[i] = t
+	}
+
+	return out, nil
+}
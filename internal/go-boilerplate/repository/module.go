@@ -2,16 +2,41 @@ package repository
 
 import (
 	"context"
-	"database/sql"
+
+	"github.com/wasay-usmani/go-boilerplate/pkg/dbkit"
 )
 
 type R interface {
-	WrapAtomic(ctx context.Context, fn func(txn *sql.Tx) error) error
+	WrapAtomic(ctx context.Context, fn dbkit.TxFunc) error
+	EnqueueEvent(ctx context.Context, topic string, payload []byte) error
 }
 
+// Module is the repository of the service. Entity repositories are built on
+// dbkit.Repository with its router, so they join the WrapAtomic transaction:
+//
+//	users := dbkit.NewRepository[*models.User, models.UserSlice](m.db, models.Users, models.UserColumns.ID)
+//
+// and map their rows to domain types with the convertors package.
 type Module struct {
+	db     *dbkit.Router
+	outbox *dbkit.Outbox
+}
+
+func NewModule(db *dbkit.Router, outbox *dbkit.Outbox) *Module {
+	return &Module{
+		db:     db,
+		outbox: outbox,
+	}
+}
+
+// WrapAtomic runs fn inside a single database transaction on the primary db.
+// Calls nested through the ctx given to fn join the transaction with a savepoint.
+func (m *Module) WrapAtomic(ctx context.Context, fn dbkit.TxFunc) error {
+	return m.db.Atomic(ctx, nil, fn)
 }
 
-func NewModule() *Module {
-	return &Module{}
+// EnqueueEvent adds an event to the outbox in the transaction carried by ctx, so it is
+// published only if the transaction commits. It must be called from a WrapAtomic fn.
+func (m *Module) EnqueueEvent(ctx context.Context, topic string, payload []byte) error {
+	return m.outbox.Enqueue(ctx, nil, topic, payload)
 }
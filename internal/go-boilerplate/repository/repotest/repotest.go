@@ -0,0 +1,26 @@
+package repotest
+
+import (
+	"testing"
+
+	"github.com/wasay-usmani/go-boilerplate/internal/go-boilerplate/migrations/app"
+	"github.com/wasay-usmani/go-boilerplate/internal/go-boilerplate/migrations/schema"
+	"github.com/wasay-usmani/go-boilerplate/internal/go-boilerplate/repository"
+	"github.com/wasay-usmani/go-boilerplate/pkg/dbkit"
+	"github.com/wasay-usmani/go-boilerplate/pkg/dbkit/dbtest"
+)
+
+// New returns the repository module on a throwaway schema migrated with the service
+// migrations, and the schema, see dbtest.New. The test is skipped when
+// TEST_DATABASE_URL is not set. Wrap the test in db.WithRollback to share the schema
+// between subtests.
+func New(t testing.TB) (*repository.Module, *dbtest.DB) {
+	t.Helper()
+
+	db := dbtest.New(t, dbtest.Migrations{Schema: schema.Assets, App: app.Assets})
+
+	// The router never closes db.Conn, dbtest does on cleanup
+	router := dbkit.NewRouter(db.Conn, nil, db.Logger)
+	outbox := dbkit.NewOutbox(db.Conn, nil, db.Logger, dbkit.WithOutboxDialect(db.Dialect))
+	return repository.NewModule(router, outbox), db
+}
@@ -1,8 +1,13 @@
 package http
 
 import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
 	"github.com/wasay-usmani/go-boilerplate/internal/go-boilerplate/app"
 	"github.com/wasay-usmani/go-boilerplate/internal/go-boilerplate/config"
+	"github.com/wasay-usmani/go-boilerplate/pkg/dbkit"
+	"github.com/wasay-usmani/go-boilerplate/pkg/httpx/echokit"
 )
 
 type H struct {
@@ -16,3 +21,18 @@ func NewHandlerBase(cfg *config.Config, appModule *app.Module) *H {
 		app: appModule,
 	}
 }
+
+// versionConflictHandler answers the version conflicts of dbkit.Versioned updates,
+// wrapping dbkit.ErrStaleVersion, with a 412 Precondition Failed carrying the ETag
+// of the current version, so the client can refetch and retry. Other errors are
+// handled by next.
+func versionConflictHandler(next echo.HTTPErrorHandler) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		if version, ok := dbkit.CurrentVersion(err); ok {
+			echokit.SetETag(c, version)
+			err = echo.NewHTTPError(http.StatusPreconditionFailed, "resource was modified, refetch it and retry").SetInternal(err)
+		}
+
+		next(err, c)
+	}
+}
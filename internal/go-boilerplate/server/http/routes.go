@@ -5,6 +5,7 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	echomiddlewarekit "github.com/wasay-usmani/go-boilerplate/pkg/httpx/echokit/middleware"
 )
 
 const (
@@ -18,13 +19,17 @@ func (h *H) LoadRoutes() http.Handler {
 	// Init router
 	e := echo.New()
 
-	// Use custom error handler
+	// Use custom error handler, answering stale row versions with a 412
+	e.HTTPErrorHandler = versionConflictHandler(e.DefaultHTTPErrorHandler)
 
 	// Load Middlewares
 	e.Use(middleware.Recover())
 
-	// Base API Group
+	// Base API Group, scoped to the tenant of the request when multi-tenant
 	v1Base := e.Group(v1BasePath)
+	if h.cfg.TenantHeader != "" {
+		v1Base.Use(echomiddlewarekit.Tenant(echomiddlewarekit.TenantConfig{Header: h.cfg.TenantHeader}))
+	}
 
 	// v1 Health Check Endpoint
 	v1Base.GET(healthPath, h.getHealth)
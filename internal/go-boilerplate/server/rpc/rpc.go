@@ -1,6 +1,8 @@
 package rpc
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
 
@@ -24,18 +26,38 @@ func NewHandlerBase(c *config.Config, application *app.Module) *H {
 }
 
 func (h *H) Run() error {
-	listener, err := net.Listen("tcp", h.conf.RPCListenPort)
+	listener, err := net.Listen("tcp", ":"+h.conf.RPC.ListenPort)
 	if err != nil {
 		return fmt.Errorf("failed to initialize rpc listener: %s", err.Error())
 	}
 
-	if err := h.server.Serve(listener); err != nil {
+	// Serve returns grpc.ErrServerStopped when a shutdown raced ahead of it
+	if err := h.server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
 		return fmt.Errorf("failed to serve: %s", err.Error())
 	}
 
 	return nil
 }
 
+// Stop gracefully stops the rpc server, waiting for pending RPCs to finish
 func (h *H) Stop() {
-	h.server.Stop()
+	h.server.GracefulStop()
+}
+
+// Shutdown gracefully stops the rpc server. If ctx expires before pending
+// RPCs finish, the server is stopped forcefully and ctx.Err() is returned.
+func (h *H) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		h.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		h.server.Stop()
+		return ctx.Err()
+	}
 }
@@ -3,32 +3,42 @@ package cachekit
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/valkey-io/valkey-go"
 	"github.com/valkey-io/valkey-go/mock"
 	"github.com/wasay-usmani/go-boilerplate/pkg/configkit"
+	"github.com/wasay-usmani/go-boilerplate/pkg/tenantkit"
 )
 
+const _clusterShardsRefreshInterval = time.Minute
+
 type valkeyClient struct {
-	client   valkey.Client
-	keySpace string
+	client       valkey.Client
+	keySpace     string
+	readTimeout  time.Duration
+	tenantScoped bool
 }
 
-func NewCache(ctx context.Context, opt *configkit.Cache) (Cache, error) {
-	options := valkey.ClientOption{
-		InitAddress:  opt.Addresses,
-		ClientName:   opt.ClientName,
-		Username:     opt.Username,
-		Password:     opt.Password,
-		DisableCache: true,
+// Option customizes the cache client
+type Option func(*valkeyClient)
+
+// WithTenantKeys prefixes every key with the tenant of the context, after the keyspace,
+// so tenants never read each other's entries, see tenantkit. Commands fail with an
+// errorx.Forbidden when the context carries no tenant.
+func WithTenantKeys() Option {
+	return func(v *valkeyClient) {
+		v.tenantScoped = true
 	}
+}
 
-	if opt.TLSEnabled != nil && *opt.TLSEnabled {
-		options.TLSConfig = &tls.Config{
-			MinVersion: tls.VersionTLS12,
-		}
+func NewCache(ctx context.Context, opt *configkit.Cache, opts ...Option) (Cache, error) {
+	options, err := clientOption(opt)
+	if err != nil {
+		return nil, err
 	}
 
 	c, err := valkey.NewClient(options)
@@ -36,7 +46,17 @@ func NewCache(ctx context.Context, opt *configkit.Cache) (Cache, error) {
 		return nil, err
 	}
 
-	client := &valkeyClient{client: c, keySpace: opt.KeySpace}
+	// valkey silently falls back to a single client when a lone address is not a cluster node
+	if opt.Mode == configkit.CacheModeCluster && c.Mode() != valkey.ClientModeCluster {
+		c.Close()
+		return nil, fmt.Errorf("cache %v is not a cluster, mode is %s", opt.Addresses, c.Mode())
+	}
+
+	client := &valkeyClient{client: c, keySpace: opt.KeySpace, readTimeout: opt.ReadTimeout}
+	for _, o := range opts {
+		o(client)
+	}
+
 	err = client.Ping(ctx)
 	if err != nil {
 		return nil, err
@@ -45,22 +65,37 @@ func NewCache(ctx context.Context, opt *configkit.Cache) (Cache, error) {
 	return client, nil
 }
 
-func NewMockClient(c *mock.Client) Cache {
-	return &valkeyClient{client: c}
+func NewMockClient(c *mock.Client, opts ...Option) Cache {
+	client := &valkeyClient{client: c}
+	for _, o := range opts {
+		o(client)
+	}
+
+	return client
 }
 
 func (v *valkeyClient) Save(ctx context.Context, key, value string, ttl time.Duration) error {
-	cmd := v.client.B().Set().Key(v.formatKey(key)).Value(value)
+	key, err := v.formatKey(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	cmd := v.client.B().Set().Key(key).Value(value)
 	if ttl > 0 {
 		cmd.Ex(ttl)
 	}
 
-	return v.client.Do(ctx, cmd.Build()).Error()
+	return v.do(ctx, cmd.Build()).Error()
 }
 
 func (v *valkeyClient) SaveKeepTTL(ctx context.Context, key, value string) error {
-	cmd := v.client.B().Set().Key(v.formatKey(key)).Value(value).Keepttl()
-	return v.client.Do(ctx, cmd.Build()).Error()
+	key, err := v.formatKey(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	cmd := v.client.B().Set().Key(key).Value(value).Keepttl()
+	return v.do(ctx, cmd.Build()).Error()
 }
 
 func (v *valkeyClient) RemoveKeys(ctx context.Context, keys ...string) error {
@@ -68,22 +103,38 @@ func (v *valkeyClient) RemoveKeys(ctx context.Context, keys ...string) error {
 		return nil
 	}
 
+	formatted := make([]string, len(keys))
 	for i := range keys {
-		keys[i] = v.formatKey(keys[i])
+		key, err := v.formatKey(ctx, keys[i])
+		if err != nil {
+			return err
+		}
+
+		formatted[i] = key
 	}
 
-	cmd := v.client.B().Del().Key(keys...).Build()
-	return v.client.Do(ctx, cmd).Error()
+	cmd := v.client.B().Del().Key(formatted...).Build()
+	return v.do(ctx, cmd).Error()
 }
 
 func (v *valkeyClient) Get(ctx context.Context, key string) (string, error) {
-	cmd := v.client.B().Get().Key(v.formatKey(key)).Build()
-	return v.client.Do(ctx, cmd).ToString()
+	key, err := v.formatKey(ctx, key)
+	if err != nil {
+		return "", err
+	}
+
+	cmd := v.client.B().Get().Key(key).Build()
+	return v.do(ctx, cmd).ToString()
 }
 
 func (v *valkeyClient) IsKeyActive(ctx context.Context, key string) (bool, error) {
-	cmd := v.client.B().Exists().Key(v.formatKey(key)).Build()
-	count, err := v.client.Do(ctx, cmd).ToInt64()
+	key, err := v.formatKey(ctx, key)
+	if err != nil {
+		return false, err
+	}
+
+	cmd := v.client.B().Exists().Key(key).Build()
+	count, err := v.do(ctx, cmd).ToInt64()
 	if err != nil {
 		return false, err
 	}
@@ -92,23 +143,43 @@ func (v *valkeyClient) IsKeyActive(ctx context.Context, key string) (bool, error
 }
 
 func (v *valkeyClient) AddSet(ctx context.Context, set string, value ...string) (int64, error) {
-	cmd := v.client.B().Sadd().Key(v.formatKey(set)).Member(value...).Build()
-	return v.client.Do(ctx, cmd).ToInt64()
+	set, err := v.formatKey(ctx, set)
+	if err != nil {
+		return 0, err
+	}
+
+	cmd := v.client.B().Sadd().Key(set).Member(value...).Build()
+	return v.do(ctx, cmd).ToInt64()
 }
 
 func (v *valkeyClient) RemoveSetValues(ctx context.Context, set string, values ...string) (int64, error) {
-	cmd := v.client.B().Srem().Key(v.formatKey(set)).Member(values...).Build()
-	return v.client.Do(ctx, cmd).ToInt64()
+	set, err := v.formatKey(ctx, set)
+	if err != nil {
+		return 0, err
+	}
+
+	cmd := v.client.B().Srem().Key(set).Member(values...).Build()
+	return v.do(ctx, cmd).ToInt64()
 }
 
 func (v *valkeyClient) RemoveSet(ctx context.Context, set string) error {
-	cmd := v.client.B().Del().Key(v.formatKey(set)).Build()
-	return v.client.Do(ctx, cmd).Error()
+	set, err := v.formatKey(ctx, set)
+	if err != nil {
+		return err
+	}
+
+	cmd := v.client.B().Del().Key(set).Build()
+	return v.do(ctx, cmd).Error()
 }
 
 func (v *valkeyClient) ContainsSet(ctx context.Context, set, value string) (bool, error) {
-	cmd := v.client.B().Sismember().Key(v.formatKey(set)).Member(value).Build()
-	res, err := v.client.Do(ctx, cmd).ToInt64()
+	set, err := v.formatKey(ctx, set)
+	if err != nil {
+		return false, err
+	}
+
+	cmd := v.client.B().Sismember().Key(set).Member(value).Build()
+	res, err := v.do(ctx, cmd).ToInt64()
 	if err != nil {
 		return false, err
 	}
@@ -118,7 +189,7 @@ func (v *valkeyClient) ContainsSet(ctx context.Context, set, value string) (bool
 
 func (v *valkeyClient) Ping(ctx context.Context) error {
 	cmd := v.client.B().Ping().Build()
-	_, err := v.client.Do(ctx, cmd).ToString()
+	_, err := v.do(ctx, cmd).ToString()
 	return err
 }
 
@@ -126,6 +197,105 @@ func (v *valkeyClient) Close() {
 	v.client.Close()
 }
 
-func (v *valkeyClient) formatKey(key string) string {
-	return fmt.Sprintf("%s::%s", v.keySpace, key)
+// do executes cmd, bounding the wait for a response by the configured read timeout
+func (v *valkeyClient) do(ctx context.Context, cmd valkey.Completed) valkey.ValkeyResult {
+	if v.readTimeout <= 0 {
+		return v.client.Do(ctx, cmd)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, v.readTimeout)
+	defer cancel()
+	return v.client.Do(ctx, cmd)
+}
+
+// formatKey namespaces key with the keyspace and, with WithTenantKeys, the tenant of ctx
+func (v *valkeyClient) formatKey(ctx context.Context, key string) (string, error) {
+	if !v.tenantScoped {
+		return fmt.Sprintf("%s::%s", v.keySpace, key), nil
+	}
+
+	tenantID, err := tenantkit.Require(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s::%s::%s", v.keySpace, tenantID, key), nil
+}
+
+// clientOption maps the cache config onto valkey client options
+func clientOption(opt *configkit.Cache) (valkey.ClientOption, error) {
+	options := valkey.ClientOption{
+		InitAddress:      opt.Addresses,
+		ClientName:       opt.ClientName,
+		Username:         opt.Username,
+		Password:         opt.Password.Value(),
+		SelectDB:         opt.DB,
+		BlockingPoolSize: opt.PoolSize,
+		ConnWriteTimeout: opt.WriteTimeout,
+		DisableCache:     true,
+	}
+
+	options.Dialer.Timeout = opt.DialTimeout
+
+	if opt.TLSEnabled != nil && *opt.TLSEnabled {
+		tlsConfig, err := newTLSConfig(opt)
+		if err != nil {
+			return valkey.ClientOption{}, err
+		}
+
+		options.TLSConfig = tlsConfig
+	}
+
+	switch opt.Mode {
+	case configkit.CacheModeStandalone:
+		options.ForceSingleClient = true
+	case configkit.CacheModeSentinel:
+		options.Sentinel = valkey.SentinelOption{
+			Dialer:     options.Dialer,
+			TLSConfig:  options.TLSConfig,
+			MasterSet:  opt.SentinelMasterSet,
+			Username:   opt.Username,
+			Password:   opt.Password.Value(),
+			ClientName: opt.ClientName,
+		}
+	case configkit.CacheModeCluster:
+		// Spread the topology discovery over the seed nodes and keep following resharding
+		options.ShuffleInit = true
+		options.ClusterOption.ShardsRefreshInterval = _clusterShardsRefreshInterval
+	case configkit.CacheModeAuto:
+		// valkey detects cluster mode from the init addresses
+	}
+
+	return options, nil
+}
+
+func newTLSConfig(opt *configkit.Cache) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if opt.CACertPath != "" {
+		caCert, err := os.ReadFile(opt.CACertPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cache ca cert: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("failed to parse cache ca cert %s", opt.CACertPath)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if opt.ClientCertPath != "" {
+		cert, err := tls.LoadX509KeyPair(opt.ClientCertPath, opt.ClientKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load cache client cert: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
 }
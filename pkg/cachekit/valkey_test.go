@@ -8,6 +8,10 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/valkey-io/valkey-go/mock"
+	"github.com/wasay-usmani/go-boilerplate/pkg/configkit"
+	"github.com/wasay-usmani/go-boilerplate/pkg/errorx"
+	"github.com/wasay-usmani/go-boilerplate/pkg/tenantkit"
+	"github.com/wasay-usmani/go-boilerplate/pkg/utils"
 	"go.uber.org/mock/gomock"
 )
 
@@ -149,3 +153,65 @@ func TestRemoveSetValues(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, int64(2), n)
 }
+
+func TestTenantKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockClient := mock.NewClient(ctrl)
+	cache := &valkeyClient{client: mockClient, keySpace: "test_keyspace"}
+	WithTenantKeys()(cache)
+
+	ctx := tenantkit.WithTenant(context.Background(), "acme")
+	mockClient.EXPECT().Do(gomock.Any(), mock.Match("GET", "test_keyspace::acme::"+testKey)).
+		Return(mock.Result(mock.ValkeyString("test_value")))
+
+	val, err := cache.Get(ctx, testKey)
+	assert.NoError(t, err)
+	assert.Equal(t, "test_value", val)
+
+	_, err = cache.Get(context.Background(), testKey)
+	assert.True(t, errorx.Is(err, errorx.Forbidden))
+
+	err = cache.RemoveKeys(context.Background(), "key1", "key2")
+	assert.True(t, errorx.Is(err, errorx.Forbidden))
+}
+
+func TestClientOption(t *testing.T) {
+	opt := &configkit.Cache{
+		Addresses:         []string{"127.0.0.1:26379"},
+		TLSEnabled:        utils.PtrOf(false),
+		KeySpace:          "test_keyspace",
+		DB:                3,
+		DialTimeout:       2 * time.Second,
+		WriteTimeout:      4 * time.Second,
+		PoolSize:          20,
+		Mode:              configkit.CacheModeSentinel,
+		SentinelMasterSet: "primary",
+	}
+
+	options, err := clientOption(opt)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, options.SelectDB)
+	assert.Equal(t, 2*time.Second, options.Dialer.Timeout)
+	assert.Equal(t, 4*time.Second, options.ConnWriteTimeout)
+	assert.Equal(t, 20, options.BlockingPoolSize)
+	assert.Equal(t, "primary", options.Sentinel.MasterSet)
+	assert.Nil(t, options.TLSConfig)
+
+	opt.Mode = configkit.CacheModeStandalone
+	opt.TLSEnabled = utils.PtrOf(true)
+	options, err = clientOption(opt)
+	assert.NoError(t, err)
+	assert.True(t, options.ForceSingleClient)
+	assert.NotNil(t, options.TLSConfig)
+
+	opt.Mode = configkit.CacheModeCluster
+	options, err = clientOption(opt)
+	assert.NoError(t, err)
+	assert.False(t, options.ForceSingleClient)
+	assert.True(t, options.ShuffleInit)
+	assert.Equal(t, _clusterShardsRefreshInterval, options.ClusterOption.ShardsRefreshInterval)
+
+	opt.CACertPath = "/does/not/exist.pem"
+	_, err = clientOption(opt)
+	assert.Error(t, err)
+}
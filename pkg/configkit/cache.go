@@ -2,33 +2,101 @@ package configkit
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/wasay-usmani/go-boilerplate/pkg/utils"
 )
 
+// CacheMode selects how the cache client discovers its topology.
+type CacheMode string
+
+const (
+	// CacheModeAuto lets the client detect a cluster or a single instance from InitAddress.
+	CacheModeAuto CacheMode = ""
+	// CacheModeStandalone forces a single instance client, even if the server reports cluster support.
+	CacheModeStandalone CacheMode = "standalone"
+	// CacheModeSentinel resolves the primary through the sentinels listed in Addresses.
+	CacheModeSentinel CacheMode = "sentinel"
+	// CacheModeCluster requires a cluster, following its topology as shards move, and fails
+	// to connect to a single instance. Clusters only have DB 0.
+	CacheModeCluster CacheMode = "cluster"
+)
+
 type Cache struct {
 	Addresses  []string `validate:"required"`
 	TLSEnabled *bool    `validate:"required"`
 	KeySpace   string   `validate:"required"`
 	Username   string
-	Password   string
+	Password   Secret
 	ClientName string
+
+	DB           int `validate:"gte=0,excluded_if=Mode cluster"`
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	PoolSize     int `validate:"gte=0"`
+
+	CACertPath     string
+	ClientCertPath string `validate:"required_with=ClientKeyPath"`
+	ClientKeyPath  string `validate:"required_with=ClientCertPath"`
+
+	Mode              CacheMode `validate:"omitempty,oneof=standalone sentinel cluster"`
+	SentinelMasterSet string    `validate:"required_if=Mode sentinel"`
 }
 
+// LoadConfig loads the cache config for the given prefix, so a service can configure
+// several cache instances. e.g. prefix = sessions reads SESSIONS_CACHE_ADDRESSES,
+// an empty prefix reads CACHE_ADDRESSES, see C.Cache. The password is read as a secret.
 func (c *C) LoadConfig(prefix string) (*Cache, error) {
-	cfg := Cache{
-		Addresses:  c.Viper.GetStringSlice("prefix_cache_addresses"),
-		Username:   c.Viper.GetString("prefix_cache_username"),
-		Password:   c.Viper.GetString("prefix_cache_password"),
-		ClientName: c.Viper.GetString("prefix_cache_client_name"),
-		TLSEnabled: utils.PtrOf(c.Viper.GetBool("prefix_cache_tls_enabled")),
-		KeySpace:   c.Viper.GetString("prefix_cache_keyspace"),
+	cfg, err := c.loadCache(prefix)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := validator.New().Struct(cfg); err != nil {
 		return nil, fmt.Errorf("cache config validation failed: %w", err)
 	}
 
-	return &cfg, nil
+	return cfg, nil
+}
+
+func (c *C) loadCache(prefix string) (*Cache, error) {
+	key := func(name string) string {
+		return cacheKey(prefix, name)
+	}
+
+	password, err := c.GetSecret(key("PASSWORD"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cache{
+		Addresses:         c.Viper.GetStringSlice(key("ADDRESSES")),
+		Username:          c.Viper.GetString(key("USERNAME")),
+		Password:          password,
+		ClientName:        c.Viper.GetString(key("CLIENT_NAME")),
+		TLSEnabled:        utils.PtrOf(c.Viper.GetBool(key("TLS_ENABLED"))),
+		KeySpace:          c.Viper.GetString(key("KEYSPACE")),
+		DB:                c.Viper.GetInt(key("DB")),
+		DialTimeout:       c.Viper.GetDuration(key("DIAL_TIMEOUT")),
+		ReadTimeout:       c.Viper.GetDuration(key("READ_TIMEOUT")),
+		WriteTimeout:      c.Viper.GetDuration(key("WRITE_TIMEOUT")),
+		PoolSize:          c.Viper.GetInt(key("POOL_SIZE")),
+		CACertPath:        c.Viper.GetString(key("CA_CERT_PATH")),
+		ClientCertPath:    c.Viper.GetString(key("CLIENT_CERT_PATH")),
+		ClientKeyPath:     c.Viper.GetString(key("CLIENT_KEY_PATH")),
+		Mode:              CacheMode(strings.ToLower(c.Viper.GetString(key("MODE")))),
+		SentinelMasterSet: c.Viper.GetString(key("SENTINEL_MASTER_SET")),
+	}, nil
+}
+
+// cacheKey returns the env var name of a cache setting, e.g. SESSIONS_CACHE_ADDRESSES.
+func cacheKey(prefix, name string) string {
+	if prefix == "" {
+		return "CACHE_" + name
+	}
+
+	return fmt.Sprintf("%s_CACHE_%s", strings.ToUpper(prefix), name)
 }
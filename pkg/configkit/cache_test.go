@@ -0,0 +1,67 @@
+package configkit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig_CachePrefix(t *testing.T) {
+	t.Setenv("SESSIONS_CACHE_ADDRESSES", "10.0.0.1:6379 10.0.0.2:6379")
+	t.Setenv("SESSIONS_CACHE_KEYSPACE", "sessions")
+	t.Setenv("SESSIONS_CACHE_DB", "2")
+	t.Setenv("SESSIONS_CACHE_DIAL_TIMEOUT", "3s")
+	t.Setenv("SESSIONS_CACHE_MODE", "Sentinel")
+	t.Setenv("SESSIONS_CACHE_SENTINEL_MASTER_SET", "primary")
+	t.Setenv("CACHE_ADDRESSES", "127.0.0.1:6379")
+	t.Setenv("CACHE_KEYSPACE", "default")
+
+	v := viper.New()
+	v.AutomaticEnv()
+	c := &C{Viper: v}
+
+	sessions, err := c.LoadConfig("sessions")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1:6379", "10.0.0.2:6379"}, sessions.Addresses)
+	assert.Equal(t, "sessions", sessions.KeySpace)
+	assert.Equal(t, 2, sessions.DB)
+	assert.Equal(t, 3*time.Second, sessions.DialTimeout)
+	assert.Equal(t, CacheModeSentinel, sessions.Mode)
+	assert.Equal(t, "primary", sessions.SentinelMasterSet)
+
+	def, err := c.LoadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"127.0.0.1:6379"}, def.Addresses)
+	assert.Equal(t, "default", def.KeySpace)
+	assert.Equal(t, CacheModeAuto, def.Mode)
+}
+
+func TestLoadConfig_CacheSentinelRequiresMasterSet(t *testing.T) {
+	t.Setenv("JOBS_CACHE_ADDRESSES", "127.0.0.1:26379")
+	t.Setenv("JOBS_CACHE_KEYSPACE", "jobs")
+	t.Setenv("JOBS_CACHE_MODE", "sentinel")
+
+	v := viper.New()
+	v.AutomaticEnv()
+	c := &C{Viper: v}
+
+	_, err := c.LoadConfig("jobs")
+	assert.ErrorContains(t, err, "SentinelMasterSet")
+}
+
+func TestLoadConfig_CacheClusterHasNoDB(t *testing.T) {
+	t.Setenv("JOBS_CACHE_ADDRESSES", "127.0.0.1:7000")
+	t.Setenv("JOBS_CACHE_KEYSPACE", "jobs")
+	t.Setenv("JOBS_CACHE_MODE", "cluster")
+	t.Setenv("JOBS_CACHE_DB", "2")
+
+	v := viper.New()
+	v.AutomaticEnv()
+	c := &C{Viper: v}
+
+	_, err := c.LoadConfig("jobs")
+	assert.ErrorContains(t, err, "excluded_if")
+}
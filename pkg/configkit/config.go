@@ -1,40 +1,307 @@
 package configkit
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
+const (
+	_defaultConfigName      = "config"
+	_defaultEnvFile         = ".env"
+	_defaultShutdownTimeout = 5 * time.Second
+)
+
 // C holds the application configuration.
+// Sections are populated by Load, the Cache section only when CACHE_ADDRESSES is set.
+// Additional prefix scoped DB and Cache sections are loaded with InitDB and LoadConfig.
 type C struct {
 	Viper *viper.Viper
 
-	AppName     string `validate:"required"`
-	AppBuild    string `validate:"required"`
-	LogLevel    string `validate:"required"`
-	Environment string `validate:"required"`
-	Debug       bool   `validate:"required"`
+	AppName         string        `env:"APP_NAME" validate:"required"`
+	AppBuild        string        `validate:"required"`
+	Environment     string        `env:"ENVIRONMENT" validate:"required"`
+	Debug           bool          `env:"DEBUG"`
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" validate:"gt=0"`
+
+	Log   Log
+	HTTP  HTTP
+	RPC   RPC
+	DB    DB
+	Cache *Cache
+
+	// opts are kept to rebuild the same layers on reload
+	opts            []Option
+	secretProviders []SecretProvider
+	sources         sources
+
+	mu         sync.RWMutex
+	secretKeys map[string]struct{}
+}
+
+// Log holds logger specific configs.
+type Log struct {
+	Level string `env:"LOG_LEVEL" validate:"required,oneof=debug info warn error fatal"`
+}
+
+// Option customizes how Load builds the configuration.
+type Option func(*options)
+
+type options struct {
+	appName     string
+	configName  string
+	configPaths []string
+	envFile     string
+	defaults    map[string]any
+	flags       *pflag.FlagSet
+	secrets     []SecretProvider
+}
+
+// WithAppName sets the default APP_NAME, it can still be overridden by any other layer.
+func WithAppName(name string) Option {
+	return func(o *options) {
+		o.appName = name
+	}
+}
+
+// WithConfigName sets the config file name (without extension), defaults to "config".
+func WithConfigName(name string) Option {
+	return func(o *options) {
+		o.configName = name
+	}
+}
+
+// WithConfigPaths sets the directories searched for the config file, in order.
+func WithConfigPaths(paths ...string) Option {
+	return func(o *options) {
+		o.configPaths = append(o.configPaths, paths...)
+	}
+}
+
+// WithEnvFile sets the dotenv file merged on top of the config file, defaults to ".env".
+// An empty path disables dotenv loading.
+func WithEnvFile(path string) Option {
+	return func(o *options) {
+		o.envFile = path
+	}
 }
 
-func LoadCacheConfig(appBuild string) (*C, error) {
+// WithDefaults registers additional default values, keyed by their env var name.
+func WithDefaults(defaults map[string]any) Option {
+	return func(o *options) {
+		for k, v := range defaults {
+			o.defaults[k] = v
+		}
+	}
+}
+
+// WithFlags binds command line flags as the highest precedence layer.
+// Flag names are mapped to keys by upper casing and replacing "-" with "_",
+// e.g. --http-listen-port overrides HTTP_LISTEN_PORT. Only flags set by the user take effect.
+func WithFlags(flags *pflag.FlagSet) Option {
+	return func(o *options) {
+		o.flags = flags
+	}
+}
+
+// WithSecretProviders registers secret providers, tried in order before the
+// built-in file:// and ${VAR} providers when resolving values read with GetSecret.
+func WithSecretProviders(providers ...SecretProvider) Option {
+	return func(o *options) {
+		o.secrets = append(o.secrets, providers...)
+	}
+}
+
+// Load builds the application configuration from the following layers,
+// each one overriding the previous:
+//
+//	defaults
+//	config file (first match of WithConfigName in WithConfigPaths)
+//	.env file
+//	environment variables
+//	flags
+//
+// The typed sections (Log, HTTP, RPC, DB, Cache) are populated and validated before returning.
+func Load(appBuild string, opts ...Option) (*C, error) {
+	cfg, err := Build(appBuild, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Build works like Load but skips validation, so an incomplete configuration
+// can still be inspected, see NewReport.
+func Build(appBuild string, opts ...Option) (*C, error) {
+	o := &options{
+		configName: _defaultConfigName,
+		envFile:    _defaultEnvFile,
+		defaults:   map[string]any{},
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	v, src, err := newViper(o)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &C{
+		Viper:           v,
+		AppName:         v.GetString(AppNameKey),
+		AppBuild:        appBuild,
+		Environment:     v.GetString(EnvironmentKey),
+		Debug:           v.GetBool(DebugKey),
+		ShutdownTimeout: v.GetDuration(ShutdownTimeoutKey),
+		Log: Log{
+			Level: v.GetString(LogLevelKey),
+		},
+		HTTP:            loadHTTP(v),
+		RPC:             loadRPC(v),
+		opts:            opts,
+		secretProviders: append(o.secrets, FileSecretProvider{}, EnvSecretProvider{}),
+		sources:         src,
+	}
+
+	if cfg.DB, err = cfg.loadDB(""); err != nil {
+		return nil, err
+	}
+
+	if v.IsSet(cacheKey("", "ADDRESSES")) {
+		if cfg.Cache, err = cfg.loadCache(""); err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// Validate validates the required fields of c.
+func (c *C) Validate() error {
+	if err := validator.New().Struct(c); err != nil {
+		return fmt.Errorf("config validation failed: %w", err)
+	}
+
+	return nil
+}
+
+func newViper(o *options) (*viper.Viper, sources, error) {
+	src := sources{flags: o.flags}
 	v := viper.New()
-	v.SetConfigType("json")
+
+	// Defaults
+	setDefaults(v)
+	if o.appName != "" {
+		v.SetDefault(AppNameKey, o.appName)
+	}
+
+	for k, val := range o.defaults {
+		v.SetDefault(k, val)
+	}
+
+	// Config file
+	v.SetConfigName(o.configName)
+	for _, p := range o.configPaths {
+		v.AddConfigPath(p)
+	}
+
+	if len(o.configPaths) > 0 {
+		if err := v.ReadInConfig(); err != nil {
+			log.Printf("Config file not found, using ENV vars. Note: This is expected behavior in k8s. Msg:	 %s\n", err)
+		} else {
+			src.fileKeys = configFileKeys(v.ConfigFileUsed())
+		}
+	}
+
+	// .env file
+	envFileKeys, err := mergeEnvFile(v, o.envFile)
+	if err != nil {
+		return nil, src, err
+	}
+
+	src.envFileKeys = envFileKeys
+
+	// Environment variables
 	v.AutomaticEnv()
 
-	cfg := &C{Viper: v, AppBuild: appBuild}
+	// Flags
+	if o.flags != nil {
+		if err := bindFlags(v, o.flags); err != nil {
+			return nil, src, err
+		}
+	}
 
-	// Populate the typed Config struct fields
-	cfg.AppName = v.GetString("app_name")
-	cfg.LogLevel = v.GetString("log_level")
-	cfg.Environment = v.GetString("environment")
-	cfg.Debug = v.GetBool("debug")
+	return v, src, nil
+}
+
+func setDefaults(v *viper.Viper) {
+	v.SetDefault(ShutdownTimeoutKey, _defaultShutdownTimeout)
+	v.SetDefault(HTTPReadTimeoutKey, _defaultHTTPReadTimeout)
+	v.SetDefault(HTTPReadHeaderTimeoutKey, _defaultHTTPReadHeaderTimeout)
+	v.SetDefault(HTTPWriteTimeoutKey, _defaultHTTPWriteTimeout)
+	v.SetDefault(HTTPIdleTimeoutKey, _defaultHTTPIdleTimeout)
+	v.SetDefault(HTTPMaxHeaderBytesKey, _defaultHTTPMaxHeaderBytes)
+	v.SetDefault(HTTPEnableKeepAliveKey, true)
+}
 
-	// Validate required fields
-	if err := validator.New().Struct(cfg); err != nil {
-		return nil, fmt.Errorf("config validation failed: %w", err)
+// mergeEnvFile merges a dotenv file into the config layer, on top of the config file.
+// It is read through its own viper instance so the main config type is left untouched.
+// The keys found in the file are returned to track where values come from.
+func mergeEnvFile(v *viper.Viper, path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
 	}
 
-	return cfg, nil
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to stat env file %s: %w", path, err)
+	}
+
+	ev := viper.New()
+	ev.SetConfigFile(path)
+	ev.SetConfigType("env")
+	if err := ev.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read env file %s: %w", path, err)
+	}
+
+	if err := v.MergeConfigMap(ev.AllSettings()); err != nil {
+		return nil, fmt.Errorf("failed to merge env file %s: %w", path, err)
+	}
+
+	return ev.AllKeys(), nil
+}
+
+func bindFlags(v *viper.Viper, flags *pflag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err != nil {
+			return
+		}
+
+		key := strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		if bindErr := v.BindPFlag(key, f); bindErr != nil {
+			err = fmt.Errorf("failed to bind flag %s: %w", f.Name, bindErr)
+		}
+	})
+
+	return err
 }
@@ -0,0 +1,102 @@
+package configkit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasay-usmani/go-boilerplate/pkg/utils"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// setDBEnv sets the required settings of the DB section.
+func setDBEnv(t *testing.T) {
+	t.Setenv("DB_SCHEMA", "app")
+	t.Setenv("DB_SUPERUSER_URL", "root:root@tcp(db:3306)/mysql")
+	t.Setenv("DB_WRITE_URL", "app:app@tcp(db:3306)/app")
+	t.Setenv("DB_READ_URL", "app:app@tcp(db:3306)/app")
+}
+
+func TestLoad_LayerPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "config.toml", `
+ENVIRONMENT = "development"
+LOG_LEVEL = "debug"
+HTTP_LISTEN_HOST = "127.0.0.1"
+HTTP_LISTEN_PORT = "8080"
+RPC_LISTEN_PORT = "9090"
+HTTP_READ_TIMEOUT = "15s"
+DB_SCHEMA = "app"
+DB_SUPERUSER_URL = "root:root@tcp(db:3306)/mysql"
+DB_WRITE_URL = "app:app@tcp(db:3306)/app"
+DB_READ_URL = "app:app@tcp(replica:3306)/app"
+DB_MAX_OPEN_CONNS = 10
+`)
+	envFile := writeFile(t, dir, ".env", "HTTP_LISTEN_PORT=8081\nRPC_LISTEN_PORT=9091\nLOG_LEVEL=warn\n")
+	t.Setenv("RPC_LISTEN_PORT", "9092")
+	t.Setenv("LOG_LEVEL", "error")
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String("log-level", "", "")
+	flags.String("http-listen-host", "", "")
+	require.NoError(t, flags.Parse([]string{"--log-level=info"}))
+
+	cfg, err := Load("build",
+		WithAppName("configkit-test"),
+		WithConfigPaths(dir),
+		WithEnvFile(envFile),
+		WithFlags(flags),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, "configkit-test", cfg.AppName)
+	assert.Equal(t, "build", cfg.AppBuild)
+	// default
+	assert.Equal(t, _defaultHTTPWriteTimeout, cfg.HTTP.WriteTimeout)
+	assert.Equal(t, _defaultShutdownTimeout, cfg.ShutdownTimeout)
+	// config file over default
+	assert.Equal(t, 15*time.Second, cfg.HTTP.ReadTimeout)
+	// unset flag does not shadow the config file
+	assert.Equal(t, "127.0.0.1", cfg.HTTP.ListenHost)
+	// .env over config file
+	assert.Equal(t, "8081", cfg.HTTP.ListenPort)
+	// env over .env
+	assert.Equal(t, "9092", cfg.RPC.ListenPort)
+	// flag over env
+	assert.Equal(t, "info", cfg.Log.Level)
+	// typed sections
+	assert.Equal(t, "app", cfg.DB.Schema)
+	assert.Equal(t, "app:app@tcp(replica:3306)/app", cfg.DB.SQLReadURL.Value())
+	assert.Equal(t, utils.PtrOf(10), cfg.DB.Pool.MaxOpenConns)
+	assert.Nil(t, cfg.Cache)
+}
+
+func TestLoad_CacheSection(t *testing.T) {
+	setDBEnv(t)
+	t.Setenv("CACHE_ADDRESSES", "cache:6379")
+	t.Setenv("CACHE_PASSWORD", "pass")
+
+	c, err := Build("build", WithEnvFile(""))
+	require.NoError(t, err)
+	require.NotNil(t, c.Cache)
+	assert.Equal(t, []string{"cache:6379"}, c.Cache.Addresses)
+	assert.Equal(t, "pass", c.Cache.Password.Value())
+
+	// The cache section is validated along with the others when present
+	assert.ErrorContains(t, c.Validate(), "C.Cache.KeySpace")
+}
+
+func TestLoad_ValidationError(t *testing.T) {
+	_, err := Load("build", WithEnvFile(""))
+	assert.ErrorContains(t, err, "config validation failed")
+}
@@ -3,24 +3,166 @@ package configkit
 import (
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
 )
 
+// DB holds the settings of a SQL database. The env tags name the keys of the
+// default section, C.DB, prefix scoped sections are loaded with InitDB.
 type DB struct {
-	SuperUserDatabaseURL string `validate:"required"`
-	Schema               string
-	SQLWriteURL          string `validate:"required"`
-	SQLReadURL           string `validate:"required"`
+	SuperUserDatabaseURL Secret `env:"DB_SUPERUSER_URL" validate:"required"`
+	Schema               string `env:"DB_SCHEMA" validate:"required"`
+	Dialect              string `env:"DB_DIALECT" validate:"omitempty,oneof=mysql postgres"`
+	SQLWriteURL          Secret `env:"DB_WRITE_URL" validate:"required"`
+	SQLReadURL           Secret `env:"DB_READ_URL" validate:"required"`
+	Pool                 DBPool
+}
+
+// DBPool holds the connection pool settings of a SQL database. Unset (nil) values
+// keep the dbkit and database/sql defaults, set ones are applied even when zero,
+// e.g. MaxIdleConns = 0 keeps no idle connection, as mapped to dbkit options by the services.
+type DBPool struct {
+	MaxOpenConns    *int           `validate:"omitempty,gte=0"`
+	MaxIdleConns    *int           `validate:"omitempty,gte=0"`
+	ConnMaxLifetime *time.Duration `validate:"omitempty,gte=0"`
+	ConnMaxIdleTime *time.Duration `validate:"omitempty,gte=0"`
+
+	// ConnectTimeout bounds each connection attempt, ConnectRetries attempts are
+	// made after the first one, waiting ConnectBackoff, doubled on every retry
+	ConnectTimeout *time.Duration `validate:"omitempty,gt=0"`
+	ConnectRetries *int           `validate:"omitempty,gte=0"`
+	ConnectBackoff *time.Duration `validate:"omitempty,gte=0"`
 }
 
 // InitDB loads database specific configs into DB struct.
 // Specified dbUrlPrefix helps to load correct env var, because
 // this method is common for all services.
-// e.g. dbUrlPrefix = DISCOUNT returns WriteURL = DISCOUNT_SQL_WRITE_URL.
-func InitDB(c *C, dbURLPrefix string) DB {
+// e.g. dbUrlPrefix = DISCOUNT returns WriteURL = DISCOUNT_DB_WRITE_URL,
+// an empty prefix returns the default section, see C.DB.
+// URLs are read as secrets, so they may be file:// or ${VAR} references.
+func InitDB(c *C, dbURLPrefix string) (DB, error) {
+	db, err := c.loadDB(dbURLPrefix)
+	if err != nil {
+		return DB{}, err
+	}
+
+	if err := db.Pool.Validate(); err != nil {
+		return DB{}, err
+	}
+
+	return db, nil
+}
+
+// Validate validates the required fields of d, e.g. for commands only using the
+// database such as migrations.
+func (d *DB) Validate() error {
+	if err := validator.New().Struct(d); err != nil {
+		return fmt.Errorf("db config validation failed: %w", err)
+	}
+
+	return nil
+}
+
+// LoadDBPool loads the pool settings for the given prefix, e.g. prefix = discount
+// reads DISCOUNT_DB_MAX_OPEN_CONNS, an empty prefix reads DB_MAX_OPEN_CONNS.
+func (c *C) LoadDBPool(prefix string) (DBPool, error) {
+	pool := loadDBPool(c.Viper, prefix)
+	if err := pool.Validate(); err != nil {
+		return DBPool{}, err
+	}
+
+	return pool, nil
+}
+
+// Validate validates the pool settings of p.
+func (p *DBPool) Validate() error {
+	if err := validator.New().Struct(p); err != nil {
+		return fmt.Errorf("db pool config validation failed: %w", err)
+	}
+
+	return nil
+}
+
+func (c *C) loadDB(prefix string) (DB, error) {
+	superUserURL, err := c.GetSecret(keyOrAlias(c.Viper, DBSuperUserURLKey, SuperUserDatabaseURLKey))
+	if err != nil {
+		return DB{}, err
+	}
+
+	writeURL, err := c.GetSecret(dbURLKey(c.Viper, prefix, "WRITE_URL", DBWriteURLKey, WriteDBURLKey))
+	if err != nil {
+		return DB{}, err
+	}
+
+	readURL, err := c.GetSecret(dbURLKey(c.Viper, prefix, "READ_URL", DBReadURLKey, ReadDBURLKey))
+	if err != nil {
+		return DB{}, err
+	}
+
 	return DB{
-		Schema:               c.Viper.GetString("DB_SCHEMA"),
-		SuperUserDatabaseURL: c.Viper.GetString("SUPERUSER_DATABASE_URL"),
-		SQLWriteURL:          c.Viper.GetString(fmt.Sprintf("%s_SQL_WRITE_URL", strings.ToUpper(dbURLPrefix))),
-		SQLReadURL:           c.Viper.GetString(fmt.Sprintf("%s_SQL_READ_URL", strings.ToUpper(dbURLPrefix))),
+		Pool:                 loadDBPool(c.Viper, prefix),
+		Schema:               c.Viper.GetString(DBSchemaKey),
+		Dialect:              c.Viper.GetString(DBDialectKey),
+		SuperUserDatabaseURL: superUserURL,
+		SQLWriteURL:          writeURL,
+		SQLReadURL:           readURL,
+	}, nil
+}
+
+func loadDBPool(v *viper.Viper, prefix string) DBPool {
+	key := func(name string) string {
+		return dbKey(v, prefix, name)
+	}
+
+	return DBPool{
+		MaxOpenConns:    setting(v, key("MAX_OPEN_CONNS"), v.GetInt),
+		MaxIdleConns:    setting(v, key("MAX_IDLE_CONNS"), v.GetInt),
+		ConnMaxLifetime: setting(v, key("CONN_MAX_LIFETIME"), v.GetDuration),
+		ConnMaxIdleTime: setting(v, key("CONN_MAX_IDLE_TIME"), v.GetDuration),
+		ConnectTimeout:  setting(v, key("CONNECT_TIMEOUT"), v.GetDuration),
+		ConnectRetries:  setting(v, key("CONNECT_RETRIES"), v.GetInt),
+		ConnectBackoff:  setting(v, key("CONNECT_BACKOFF"), v.GetDuration),
+	}
+}
+
+// setting reads key with get, nil when no layer sets it.
+func setting[T any](v *viper.Viper, key string, get func(string) T) *T {
+	if !v.IsSet(key) {
+		return nil
+	}
+
+	value := get(key)
+	return &value
+}
+
+// dbURLKey returns the env var name of a database URL, key or its alias without a
+// prefix, e.g. DB_WRITE_URL, and DISCOUNT_DB_WRITE_URL with prefix = discount.
+func dbURLKey(v *viper.Viper, prefix, name, key, alias string) string {
+	if prefix == "" {
+		return keyOrAlias(v, key, alias)
+	}
+
+	return dbKey(v, prefix, name)
+}
+
+// dbKey returns the env var name of a db setting, e.g. DB_MAX_OPEN_CONNS or
+// DISCOUNT_DB_MAX_OPEN_CONNS, or its SQL_ alias, e.g. DISCOUNT_SQL_MAX_OPEN_CONNS.
+func dbKey(v *viper.Viper, prefix, name string) string {
+	if prefix == "" {
+		return keyOrAlias(v, "DB_"+name, "SQL_"+name)
+	}
+
+	prefix = strings.ToUpper(prefix)
+	return keyOrAlias(v, fmt.Sprintf("%s_DB_%s", prefix, name), fmt.Sprintf("%s_SQL_%s", prefix, name))
+}
+
+// keyOrAlias returns key, or its deprecated alias when only the alias is set.
+func keyOrAlias(v *viper.Viper, key, alias string) string {
+	if !v.IsSet(key) && v.IsSet(alias) {
+		return alias
 	}
+
+	return key
 }
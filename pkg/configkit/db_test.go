@@ -0,0 +1,112 @@
+package configkit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasay-usmani/go-boilerplate/pkg/utils"
+)
+
+func TestLoadDBPool(t *testing.T) {
+	t.Setenv("DISCOUNT_DB_MAX_OPEN_CONNS", "20")
+	t.Setenv("DISCOUNT_DB_MAX_IDLE_CONNS", "10")
+	t.Setenv("DISCOUNT_DB_CONN_MAX_LIFETIME", "5m")
+	t.Setenv("DISCOUNT_DB_CONNECT_RETRIES", "3")
+	t.Setenv("DISCOUNT_DB_CONNECT_BACKOFF", "500ms")
+	t.Setenv("DB_MAX_OPEN_CONNS", "5")
+
+	v := viper.New()
+	v.AutomaticEnv()
+	c := &C{Viper: v}
+
+	discount, err := c.LoadDBPool("discount")
+	require.NoError(t, err)
+	assert.Equal(t, DBPool{
+		MaxOpenConns:    utils.PtrOf(20),
+		MaxIdleConns:    utils.PtrOf(10),
+		ConnMaxLifetime: utils.PtrOf(5 * time.Minute),
+		ConnectRetries:  utils.PtrOf(3),
+		ConnectBackoff:  utils.PtrOf(500 * time.Millisecond),
+	}, discount)
+
+	def, err := c.LoadDBPool("")
+	require.NoError(t, err)
+	assert.Equal(t, utils.PtrOf(5), def.MaxOpenConns)
+}
+
+func TestLoadDBPool_ZeroIsSet(t *testing.T) {
+	t.Setenv("DB_MAX_IDLE_CONNS", "0")
+	t.Setenv("DB_CONN_MAX_LIFETIME", "0s")
+
+	v := viper.New()
+	v.AutomaticEnv()
+	c := &C{Viper: v}
+
+	pool, err := c.LoadDBPool("")
+	require.NoError(t, err)
+	assert.Equal(t, utils.PtrOf(0), pool.MaxIdleConns)
+	assert.Equal(t, utils.PtrOf(time.Duration(0)), pool.ConnMaxLifetime)
+	assert.Nil(t, pool.MaxOpenConns)
+}
+
+func TestLoadDBPool_Validation(t *testing.T) {
+	t.Setenv("DB_MAX_OPEN_CONNS", "-1")
+
+	v := viper.New()
+	v.AutomaticEnv()
+	c := &C{Viper: v}
+
+	_, err := c.LoadDBPool("")
+	assert.ErrorContains(t, err, "MaxOpenConns")
+}
+
+func TestInitDB_Prefix(t *testing.T) {
+	t.Setenv("DB_SCHEMA", "app")
+	t.Setenv("DB_WRITE_URL", "app:app@tcp(db:3306)/app")
+	t.Setenv("DISCOUNT_DB_WRITE_URL", "discount:discount@tcp(db:3306)/discount")
+	t.Setenv("DISCOUNT_DB_READ_URL", "discount:discount@tcp(replica:3306)/discount")
+
+	v := viper.New()
+	v.AutomaticEnv()
+	c := &C{Viper: v}
+
+	discount, err := InitDB(c, "discount")
+	require.NoError(t, err)
+	assert.Equal(t, "discount:discount@tcp(db:3306)/discount", discount.SQLWriteURL.Value())
+	assert.Equal(t, "discount:discount@tcp(replica:3306)/discount", discount.SQLReadURL.Value())
+	assert.Equal(t, "app", discount.Schema)
+
+	def, err := InitDB(c, "")
+	require.NoError(t, err)
+	assert.Equal(t, "app:app@tcp(db:3306)/app", def.SQLWriteURL.Value())
+	assert.ErrorContains(t, def.Validate(), "SQLReadURL")
+}
+
+func TestInitDB_Aliases(t *testing.T) {
+	t.Setenv("SUPERUSER_DATABASE_URL", "root:root@tcp(db:3306)/mysql")
+	t.Setenv("WRITE_DB_URL", "app:app@tcp(db:3306)/app")
+	t.Setenv("DB_READ_URL", "app:app@tcp(replica:3306)/app")
+	t.Setenv("READ_DB_URL", "old:old@tcp(replica:3306)/app")
+	t.Setenv("SQL_MAX_OPEN_CONNS", "5")
+	t.Setenv("DISCOUNT_SQL_WRITE_URL", "discount:discount@tcp(db:3306)/discount")
+
+	v := viper.New()
+	v.AutomaticEnv()
+	c := &C{Viper: v}
+
+	def, err := InitDB(c, "")
+	require.NoError(t, err)
+	assert.Equal(t, "root:root@tcp(db:3306)/mysql", def.SuperUserDatabaseURL.Value())
+	assert.Equal(t, "app:app@tcp(db:3306)/app", def.SQLWriteURL.Value())
+	assert.Equal(t, utils.PtrOf(5), def.Pool.MaxOpenConns)
+
+	// The DB_ key wins over its alias
+	assert.Equal(t, "app:app@tcp(replica:3306)/app", def.SQLReadURL.Value())
+
+	discount, err := InitDB(c, "discount")
+	require.NoError(t, err)
+	assert.Equal(t, "discount:discount@tcp(db:3306)/discount", discount.SQLWriteURL.Value())
+}
@@ -2,28 +2,54 @@ package configkit
 
 import (
 	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+)
+
+const (
+	_defaultHTTPReadTimeout       = 30 * time.Second
+	_defaultHTTPReadHeaderTimeout = 10 * time.Second
+	_defaultHTTPWriteTimeout      = 30 * time.Second
+	_defaultHTTPIdleTimeout       = 5 * time.Minute
+	_defaultHTTPMaxHeaderBytes    = http.DefaultMaxHeaderBytes
 )
 
 type HTTP struct {
-	ListenHost      string `validate:"required"`
-	ListenPort      string `validate:"required"`
-	Timeout         int
-	EnableKeepAlive bool
+	ListenHost        string        `env:"HTTP_LISTEN_HOST" validate:"required"`
+	ListenPort        string        `env:"HTTP_LISTEN_PORT" validate:"required"`
+	ReadTimeout       time.Duration `env:"HTTP_READ_TIMEOUT" validate:"gt=0"`
+	ReadHeaderTimeout time.Duration `env:"HTTP_READ_HEADER_TIMEOUT" validate:"gt=0"`
+	WriteTimeout      time.Duration `env:"HTTP_WRITE_TIMEOUT" validate:"gt=0"`
+	IdleTimeout       time.Duration `env:"HTTP_IDLE_TIMEOUT" validate:"gt=0"`
+	MaxHeaderBytes    int           `env:"HTTP_MAX_HEADER_BYTES" validate:"gt=0"`
+	EnableKeepAlive   bool          `env:"HTTP_ENABLE_KEEP_ALIVE"`
+	// MetricsListenPort serves the metrics apart from the API, so they are not exposed with it. Empty disables them
+	MetricsListenPort string `env:"HTTP_METRICS_LISTEN_PORT" validate:"omitempty,nefield=ListenPort"`
 }
 
+// LoadHTTPConfig loads and validates the HTTP section on its own.
 func (c *C) LoadHTTPConfig() (*HTTP, error) {
-	cfg := &HTTP{
-		ListenHost:      c.Viper.GetString("http_listen_host"),
-		ListenPort:      c.Viper.GetString("http_listen_port"),
-		Timeout:         c.Viper.GetInt("http_timeout"),
-		EnableKeepAlive: c.Viper.GetBool("http_enable_keep_alive"),
-	}
-
+	cfg := loadHTTP(c.Viper)
 	if err := validator.New().Struct(cfg); err != nil {
 		return nil, fmt.Errorf("http config validation failed: %w", err)
 	}
 
-	return cfg, nil
+	return &cfg, nil
+}
+
+func loadHTTP(v *viper.Viper) HTTP {
+	return HTTP{
+		ListenHost:        v.GetString(HTTPListenHostKey),
+		ListenPort:        v.GetString(HTTPListenPortKey),
+		ReadTimeout:       v.GetDuration(HTTPReadTimeoutKey),
+		ReadHeaderTimeout: v.GetDuration(HTTPReadHeaderTimeoutKey),
+		WriteTimeout:      v.GetDuration(HTTPWriteTimeoutKey),
+		IdleTimeout:       v.GetDuration(HTTPIdleTimeoutKey),
+		MaxHeaderBytes:    v.GetInt(HTTPMaxHeaderBytesKey),
+		EnableKeepAlive:   v.GetBool(HTTPEnableKeepAliveKey),
+		MetricsListenPort: v.GetString(HTTPMetricsListenPortKey),
+	}
 }
@@ -0,0 +1,41 @@
+package configkit
+
+// Config keys, named after the environment variables they are read from.
+// The same names are used in config files, .env files and (lower-kebab cased) flags.
+const (
+	AppNameKey         = "APP_NAME"
+	EnvironmentKey     = "ENVIRONMENT"
+	DebugKey           = "DEBUG"
+	LogLevelKey        = "LOG_LEVEL"
+	ShutdownTimeoutKey = "SHUTDOWN_TIMEOUT"
+
+	HTTPListenHostKey        = "HTTP_LISTEN_HOST"
+	HTTPListenPortKey        = "HTTP_LISTEN_PORT"
+	HTTPReadTimeoutKey       = "HTTP_READ_TIMEOUT"
+	HTTPReadHeaderTimeoutKey = "HTTP_READ_HEADER_TIMEOUT"
+	HTTPWriteTimeoutKey      = "HTTP_WRITE_TIMEOUT"
+	HTTPIdleTimeoutKey       = "HTTP_IDLE_TIMEOUT"
+	HTTPMaxHeaderBytesKey    = "HTTP_MAX_HEADER_BYTES"
+	HTTPEnableKeepAliveKey   = "HTTP_ENABLE_KEEP_ALIVE"
+	HTTPMetricsListenPortKey = "HTTP_METRICS_LISTEN_PORT"
+
+	RPCListenPortKey = "RPC_LISTEN_PORT"
+
+	DBSchemaKey       = "DB_SCHEMA"
+	DBDialectKey      = "DB_DIALECT"
+	DBSuperUserURLKey = "DB_SUPERUSER_URL"
+	DBWriteURLKey     = "DB_WRITE_URL"
+	DBReadURLKey      = "DB_READ_URL"
+)
+
+// Former names of the DB keys, still read when the DB_ key they alias is not set.
+// The pool settings are aliased the same way, SQL_MAX_OPEN_CONNS for DB_MAX_OPEN_CONNS
+// and DISCOUNT_SQL_WRITE_URL for DISCOUNT_DB_WRITE_URL.
+const (
+	// Deprecated: use DBSuperUserURLKey.
+	SuperUserDatabaseURLKey = "SUPERUSER_DATABASE_URL"
+	// Deprecated: use DBWriteURLKey.
+	WriteDBURLKey = "WRITE_DB_URL"
+	// Deprecated: use DBReadURLKey.
+	ReadDBURLKey = "READ_DB_URL"
+)
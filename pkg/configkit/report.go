@@ -0,0 +1,275 @@
+package configkit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Source is the configuration layer an effective value comes from.
+type Source string
+
+const (
+	SourceUnset   Source = "unset"
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnvFile Source = ".env"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// _secretKeyParts mark the keys holding secrets, matched against the _ separated parts of a key
+var _secretKeyParts = []string{"PASSWORD", "PASSWD", "SECRET", "TOKEN", "KEY", "CREDENTIALS"}
+
+// _credentialsPattern matches URLs and DSNs with a password, e.g. postgres://app:pass@db
+// or app:pass@tcp(db:3306)/app
+var _credentialsPattern = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9+.-]*://)?[^@/:]*:[^@]*@`)
+
+// Report describes the effective configuration, every known key with its
+// redacted value and source, along with all validation issues.
+type Report struct {
+	ConfigFile string
+	Entries    []Entry
+	Issues     []Issue
+}
+
+// Entry is a single effective configuration value.
+type Entry struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source Source `json:"source"`
+}
+
+// Issue is a missing or invalid configuration field.
+// EnvVar is derived from the field's env tag and is empty for prefix scoped sections.
+type Issue struct {
+	Field  string `json:"field"`
+	EnvVar string `json:"env_var,omitempty"`
+	Rule   string `json:"rule"`
+	Param  string `json:"param,omitempty"`
+}
+
+// sources keeps track of the keys set by each layer, see Source.
+type sources struct {
+	fileKeys    []string
+	envFileKeys []string
+	flags       *pflag.FlagSet
+}
+
+// Source returns the highest precedence layer that sets key.
+func (c *C) Source(key string) Source {
+	key = strings.ToUpper(key)
+	lower := strings.ToLower(key)
+
+	if f := c.flagFor(key); f != nil && f.Changed {
+		return SourceFlag
+	}
+
+	// Empty env vars are ignored by viper
+	if os.Getenv(key) != "" {
+		return SourceEnv
+	}
+
+	if slices.Contains(c.sources.envFileKeys, lower) {
+		return SourceEnvFile
+	}
+
+	if slices.Contains(c.sources.fileKeys, lower) {
+		return SourceFile
+	}
+
+	if c.Viper.IsSet(key) {
+		return SourceDefault
+	}
+
+	return SourceUnset
+}
+
+// NewReport builds a report of c. cfg is the struct that is validated, usually
+// the service config embedding c. Its env tags are used to list keys that are
+// not set anywhere and to name the env var of each validation issue.
+// Secrets are redacted whether they were read or not, see isSecretKey.
+func NewReport(c *C, cfg any) *Report {
+	keys := envKeys(reflect.TypeOf(cfg))
+	for _, k := range c.Viper.AllKeys() {
+		keys = append(keys, strings.ToUpper(k))
+	}
+
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
+	r := &Report{ConfigFile: c.Viper.ConfigFileUsed()}
+	for _, k := range keys {
+		value := fmt.Sprint(c.Viper.Get(k))
+		if c.isSecretKey(k, value) {
+			value = Secret(value).String()
+		}
+
+		source := c.Source(k)
+		if source == SourceUnset {
+			value = ""
+		}
+
+		r.Entries = append(r.Entries, Entry{Key: k, Value: value, Source: source})
+	}
+
+	r.Issues = validationIssues(cfg)
+	return r
+}
+
+// Valid reports whether the configuration has no validation issues.
+func (r *Report) Valid() bool {
+	return len(r.Issues) == 0
+}
+
+// Write prints the report as aligned tables.
+func (r *Report) Write(w io.Writer) error {
+	configFile := r.ConfigFile
+	if configFile == "" {
+		configFile = "none"
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Config file: %s\n\n", configFile)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, e := range r.Entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", e.Key, e.Value, e.Source)
+	}
+
+	if r.Valid() {
+		fmt.Fprintln(tw, "\nValidation: ok")
+		return tw.Flush()
+	}
+
+	fmt.Fprintf(tw, "\nValidation: %d issue(s)\n", len(r.Issues))
+	fmt.Fprintln(tw, "FIELD\tENV VAR\tRULE")
+	for _, i := range r.Issues {
+		rule := i.Rule
+		if i.Param != "" {
+			rule = fmt.Sprintf("%s=%s", i.Rule, i.Param)
+		}
+
+		envVar := i.EnvVar
+		if envVar == "" {
+			envVar = "-"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", i.Field, envVar, rule)
+	}
+
+	return tw.Flush()
+}
+
+// isSecretKey reports whether the value of key is a secret: the key was read through
+// GetSecret, is named like a secret, e.g. CACHE_PASSWORD, or holds a URL with credentials.
+func (c *C) isSecretKey(key, value string) bool {
+	if c.IsSecret(key) {
+		return true
+	}
+
+	parts := strings.Split(strings.ToUpper(key), "_")
+	for _, part := range _secretKeyParts {
+		if slices.Contains(parts, part) {
+			return true
+		}
+	}
+
+	return (slices.Contains(parts, "URL") || slices.Contains(parts, "DSN")) && _credentialsPattern.MatchString(value)
+}
+
+func (c *C) flagFor(key string) *pflag.Flag {
+	if c.sources.flags == nil {
+		return nil
+	}
+
+	return c.sources.flags.Lookup(strings.ToLower(strings.ReplaceAll(key, "_", "-")))
+}
+
+// configFileKeys returns the keys set in the config file itself, excluding defaults.
+func configFileKeys(file string) []string {
+	fv := viper.New()
+	fv.SetConfigFile(file)
+	if err := fv.ReadInConfig(); err != nil {
+		return nil
+	}
+
+	return fv.AllKeys()
+}
+
+// envKeys collects the env tags of t, walking nested and embedded structs.
+func envKeys(t reflect.Type) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var keys []string
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		if env := f.Tag.Get("env"); env != "" {
+			keys = append(keys, env)
+			continue
+		}
+
+		keys = append(keys, envKeys(f.Type)...)
+	}
+
+	return keys
+}
+
+func validationIssues(cfg any) []Issue {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(f reflect.StructField) string {
+		return f.Tag.Get("env")
+	})
+
+	err := validate.Struct(cfg)
+	if err == nil {
+		return nil
+	}
+
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return []Issue{{Field: "-", Rule: err.Error()}}
+	}
+
+	issues := make([]Issue, 0, len(verrs))
+	for _, fe := range verrs {
+		issue := Issue{
+			Field: fe.StructNamespace(),
+			Rule:  fe.Tag(),
+			Param: fe.Param(),
+		}
+
+		// Without an env tag the validator falls back to the struct field name
+		if fe.Field() != fe.StructField() {
+			issue.EnvVar = fe.Field()
+		}
+
+		// Drop the root type name, e.g. Config.C.HTTP.ListenPort -> C.HTTP.ListenPort
+		if _, field, ok := strings.Cut(issue.Field, "."); ok {
+			issue.Field = field
+		}
+
+		issues = append(issues, issue)
+	}
+
+	return issues
+}
@@ -0,0 +1,96 @@
+package configkit
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type reportConfig struct {
+	*C
+
+	DatabaseURL Secret `env:"DATABASE_URL" validate:"required"`
+	Schema      string `validate:"required"`
+}
+
+func TestNewReport(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "config.toml", `
+ENVIRONMENT = "development"
+LOG_LEVEL = "debug"
+HTTP_LISTEN_HOST = "127.0.0.1"
+DB_SCHEMA = "app"
+DB_SUPERUSER_URL = "root:root@tcp(db:3306)/mysql"
+DB_WRITE_URL = "app:pass@tcp(db:3306)/app"
+DB_READ_URL = "app:pass@tcp(db:3306)/app"
+`)
+	envFile := writeFile(t, dir, ".env", "HTTP_LISTEN_PORT=8080\nSESSIONS_CACHE_PASSWORD=cache-pass\nSESSIONS_CACHE_KEYSPACE=sessions\n"+
+		"EVENTS_DB_WRITE_URL=postgres://events:events-pass@db:5432/events\n")
+	t.Setenv("DATABASE_URL", "mysql://app:pass@db/app")
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String("log-level", "", "")
+	require.NoError(t, flags.Parse([]string{"--log-level=info"}))
+
+	c, err := Build("build", WithAppName("configkit-test"), WithConfigPaths(dir), WithEnvFile(envFile), WithFlags(flags))
+	require.NoError(t, err)
+
+	dbURL, err := c.GetSecret("DATABASE_URL")
+	require.NoError(t, err)
+
+	report := NewReport(c, &reportConfig{C: c, DatabaseURL: dbURL})
+
+	entries := map[string]Entry{}
+	for _, e := range report.Entries {
+		entries[e.Key] = e
+	}
+
+	assert.Equal(t, Entry{Key: "APP_NAME", Value: "configkit-test", Source: SourceDefault}, entries["APP_NAME"])
+	assert.Equal(t, Entry{Key: "HTTP_LISTEN_HOST", Value: "127.0.0.1", Source: SourceFile}, entries["HTTP_LISTEN_HOST"])
+	assert.Equal(t, Entry{Key: "HTTP_LISTEN_PORT", Value: "8080", Source: SourceEnvFile}, entries["HTTP_LISTEN_PORT"])
+	assert.Equal(t, Entry{Key: "DATABASE_URL", Value: "[REDACTED]", Source: SourceEnv}, entries["DATABASE_URL"])
+	assert.Equal(t, Entry{Key: "DB_WRITE_URL", Value: "[REDACTED]", Source: SourceFile}, entries["DB_WRITE_URL"])
+	// Secrets that were never read are redacted by name or by the credentials they hold
+	assert.Equal(t, Entry{Key: "SESSIONS_CACHE_PASSWORD", Value: "[REDACTED]", Source: SourceEnvFile}, entries["SESSIONS_CACHE_PASSWORD"])
+	assert.Equal(t, Entry{Key: "EVENTS_DB_WRITE_URL", Value: "[REDACTED]", Source: SourceEnvFile}, entries["EVENTS_DB_WRITE_URL"])
+	assert.Equal(t, Entry{Key: "SESSIONS_CACHE_KEYSPACE", Value: "sessions", Source: SourceEnvFile}, entries["SESSIONS_CACHE_KEYSPACE"])
+	assert.Equal(t, Entry{Key: "LOG_LEVEL", Value: "info", Source: SourceFlag}, entries["LOG_LEVEL"])
+	assert.Equal(t, Entry{Key: "RPC_LISTEN_PORT", Value: "", Source: SourceUnset}, entries["RPC_LISTEN_PORT"])
+
+	assert.False(t, report.Valid())
+	assert.ElementsMatch(t, []Issue{
+		{Field: "C.RPC.ListenPort", EnvVar: "RPC_LISTEN_PORT", Rule: "required"},
+		{Field: "Schema", Rule: "required"},
+	}, report.Issues)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, report.Write(buf))
+	assert.Contains(t, buf.String(), "Validation: 2 issue(s)")
+	assert.NotContains(t, buf.String(), "app:pass")
+	assert.NotContains(t, buf.String(), "cache-pass")
+	assert.NotContains(t, buf.String(), "events-pass")
+}
+
+func TestIsSecretKey(t *testing.T) {
+	c := &C{}
+	tests := []struct {
+		key, value string
+		secret     bool
+	}{
+		{"CACHE_PASSWORD", "pass", true},
+		{"API_TOKEN", "token", true},
+		{"CURSOR_KEY", "key", true},
+		{"CACHE_KEYSPACE", "app", false},
+		{"DB_READ_URL", "app:pass@tcp(db:3306)/app", true},
+		{"EVENTS_DB_READ_URL", "postgres://app:pass@db/app", true},
+		{"DB_READ_URL", "postgres://db/app?sslmode=disable", false},
+		{"METRICS_URL", "http://metrics:9090/push", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.secret, c.isSecretKey(tt.key, tt.value), tt.key+"="+tt.value)
+	}
+}
@@ -1,11 +1,17 @@
 package configkit
 
+import "github.com/spf13/viper"
+
 type RPC struct {
-	ListenPort string `validate:"required"`
+	ListenPort string `env:"RPC_LISTEN_PORT" validate:"required"`
 }
 
 func InitRPC(c *C) RPC {
+	return loadRPC(c.Viper)
+}
+
+func loadRPC(v *viper.Viper) RPC {
 	return RPC{
-		ListenPort: c.Viper.GetString("RPC_LISTEN_PORT"),
+		ListenPort: v.GetString(RPCListenPortKey),
 	}
 }
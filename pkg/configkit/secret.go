@@ -0,0 +1,125 @@
+package configkit
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	_redacted         = "[REDACTED]"
+	_fileSecretPrefix = "file://"
+)
+
+var _envRefPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Secret is a resolved secret value. It redacts itself when formatted, logged or
+// marshaled to JSON, use Value to read the actual secret.
+type Secret string
+
+// SecretProvider resolves secret references found in config values.
+// Resolve reports ok = false when ref is not a reference it handles.
+type SecretProvider interface {
+	Resolve(ref string) (value string, ok bool, err error)
+}
+
+// FileSecretProvider resolves file:///path/to/secret references to the file content,
+// e.g. Kubernetes or Docker mounted secrets. Trailing newlines are trimmed.
+type FileSecretProvider struct{}
+
+// EnvSecretProvider resolves ${OTHER_VAR} references from the environment.
+// References can be embedded, e.g. postgres://app:${DB_PASSWORD}@db:5432/app.
+type EnvSecretProvider struct{}
+
+// Value returns the actual secret value.
+func (s Secret) Value() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+
+	return _redacted
+}
+
+func (s Secret) GoString() string {
+	return fmt.Sprintf("%q", s.String())
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return fmt.Appendf(nil, "%q", s.String()), nil
+}
+
+func (FileSecretProvider) Resolve(ref string) (value string, ok bool, err error) {
+	if !strings.HasPrefix(ref, _fileSecretPrefix) {
+		return "", false, nil
+	}
+
+	path := strings.TrimPrefix(ref, _fileSecretPrefix)
+	content, err := os.ReadFile(path) // #nosec G304 -- path comes from trusted configuration
+	if err != nil {
+		return "", true, fmt.Errorf("failed to read secret file %s: %w", path, err)
+	}
+
+	return strings.TrimRight(string(content), "\r\n"), true, nil
+}
+
+func (EnvSecretProvider) Resolve(ref string) (value string, ok bool, err error) {
+	if !_envRefPattern.MatchString(ref) {
+		return "", false, nil
+	}
+
+	value = _envRefPattern.ReplaceAllStringFunc(ref, func(match string) string {
+		name := _envRefPattern.FindStringSubmatch(match)[1]
+		v, found := os.LookupEnv(name)
+		if !found && err == nil {
+			err = fmt.Errorf("secret reference ${%s} is not set", name)
+		}
+
+		return v
+	})
+
+	return value, true, err
+}
+
+// GetSecret reads key and resolves it through the secret providers.
+// Values that are not references are returned as is. The key is
+// remembered as a secret so it is redacted when the config is dumped.
+func (c *C) GetSecret(key string) (Secret, error) {
+	c.markSecret(key)
+
+	raw := c.Viper.GetString(key)
+	for _, p := range c.secretProviders {
+		value, ok, err := p.Resolve(raw)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve secret %s: %w", key, err)
+		}
+
+		if ok {
+			return Secret(value), nil
+		}
+	}
+
+	return Secret(raw), nil
+}
+
+// IsSecret reports whether key was read through GetSecret.
+func (c *C) IsSecret(key string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.secretKeys[strings.ToUpper(key)]
+	return ok
+}
+
+func (c *C) markSecret(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.secretKeys == nil {
+		c.secretKeys = map[string]struct{}{}
+	}
+
+	c.secretKeys[strings.ToUpper(key)] = struct{}{}
+}
@@ -0,0 +1,86 @@
+package configkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticProvider map[string]string
+
+func (p staticProvider) Resolve(ref string) (value string, ok bool, err error) {
+	value, ok = p[ref]
+	return value, ok, nil
+}
+
+func newSecretConfig(providers ...SecretProvider) *C {
+	v := viper.New()
+	v.AutomaticEnv()
+	return &C{Viper: v, secretProviders: append(providers, FileSecretProvider{}, EnvSecretProvider{})}
+}
+
+func TestGetSecret_Providers(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "db_url", "mysql://app:pass@db/app\n")
+
+	t.Setenv("FILE_SECRET", "file://"+path)
+	t.Setenv("DB_PASSWORD", "s3cret")
+	t.Setenv("ENV_SECRET", "mysql://app:${DB_PASSWORD}@db/app")
+	t.Setenv("PLAIN_SECRET", "plain")
+	t.Setenv("VAULT_SECRET", "vault://db")
+
+	c := newSecretConfig(staticProvider{"vault://db": "from-vault"})
+
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{"FILE_SECRET", "mysql://app:pass@db/app"},
+		{"ENV_SECRET", "mysql://app:s3cret@db/app"},
+		{"PLAIN_SECRET", "plain"},
+		{"VAULT_SECRET", "from-vault"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			secret, err := c.GetSecret(tt.key)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, secret.Value())
+			assert.True(t, c.IsSecret(tt.key))
+		})
+	}
+
+	assert.False(t, c.IsSecret("DB_PASSWORD"))
+}
+
+func TestGetSecret_UnresolvedReference(t *testing.T) {
+	t.Setenv("MISSING_FILE", "file:///does/not/exist")
+	t.Setenv("MISSING_ENV", "${DOES_NOT_EXIST_VAR}")
+
+	c := newSecretConfig()
+
+	_, err := c.GetSecret("MISSING_FILE")
+	assert.Error(t, err)
+
+	_, err = c.GetSecret("MISSING_ENV")
+	assert.ErrorContains(t, err, "DOES_NOT_EXIST_VAR")
+}
+
+func TestSecret_Redacted(t *testing.T) {
+	s := Secret("hunter2")
+	cfg := struct {
+		Password Secret
+	}{Password: s}
+
+	assert.Equal(t, "hunter2", s.Value())
+	assert.NotContains(t, fmt.Sprintf("%v %+v %#v %s", cfg, cfg, cfg, s), "hunter2")
+
+	out, err := json.Marshal(cfg)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Password":"[REDACTED]"}`, string(out))
+	assert.Empty(t, Secret("").String())
+}
@@ -0,0 +1,176 @@
+package configkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/wasay-usmani/go-boilerplate/pkg/logkit"
+)
+
+// ErrNoConfigFile is returned by Watch when the configuration was not loaded from a file.
+var ErrNoConfigFile = errors.New("no config file in use, nothing to watch")
+
+// ChangeFunc is notified after a configuration change has been validated and applied.
+type ChangeFunc func(prev, next *C)
+
+// ValidateFunc can reject a reloaded configuration before any subscriber sees it.
+type ValidateFunc func(next *C) error
+
+// Watcher reloads the configuration whenever its config file changes.
+// Every reload re-runs all layers of Load, so env vars and flags keep their precedence.
+// Only the config file is watched, env vars and flags require a restart to change.
+type Watcher struct {
+	mu         sync.RWMutex
+	current    *C
+	validators []ValidateFunc
+	subs       []ChangeFunc
+	logger     logkit.Logger
+
+	file      string
+	fsWatcher *fsnotify.Watcher
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Watch starts watching the config file used by c and returns a Watcher
+// components can subscribe to. The watch lasts until Close is called, or
+// until the context of Run is done.
+func (c *C) Watch(l logkit.Logger) (*Watcher, error) {
+	file := c.Viper.ConfigFileUsed()
+	if file == "" {
+		return nil, ErrNoConfigFile
+	}
+
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch config file %s: %w", file, err)
+	}
+
+	// The directory is watched to pick up atomic saves and replaced symlinks,
+	// e.g. Kubernetes ConfigMap updates
+	file = filepath.Clean(file)
+	if err := fsWatcher.Add(filepath.Dir(file)); err != nil {
+		_ = fsWatcher.Close()
+		return nil, fmt.Errorf("failed to watch config file %s: %w", file, err)
+	}
+
+	w := &Watcher{
+		current:   c,
+		logger:    l,
+		file:      file,
+		fsWatcher: fsWatcher,
+		done:      make(chan struct{}),
+	}
+
+	go w.watch()
+	return w, nil
+}
+
+// Run blocks until ctx is done and then stops the watch, so the Watcher can be
+// registered as a runkit worker.
+func (w *Watcher) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return w.Close()
+}
+
+// Close stops watching the config file and waits for an ongoing reload to
+// complete. It is safe to call more than once.
+func (w *Watcher) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		err = w.fsWatcher.Close()
+		<-w.done
+	})
+
+	return err
+}
+
+// Current returns the latest accepted configuration.
+func (w *Watcher) Current() *C {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// AddValidator registers a validation step run on every reload.
+// A reload is rejected and logged if any validator returns an error.
+func (w *Watcher) AddValidator(fn ValidateFunc) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.validators = append(w.validators, fn)
+}
+
+// Subscribe registers fn to be called with the previous and new configuration after each accepted reload.
+// Subscribers are called sequentially, in registration order, and should return quickly.
+func (w *Watcher) Subscribe(fn ChangeFunc) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subs = append(w.subs, fn)
+}
+
+func (w *Watcher) watch() {
+	defer close(w.done)
+
+	realFile, _ := filepath.EvalSymlinks(w.file)
+	for {
+		select {
+		case e, ok := <-w.fsWatcher.Events:
+			if !ok {
+				return
+			}
+
+			// Reload when the file is written or created, or when the file it links to changed
+			currentFile, _ := filepath.EvalSymlinks(w.file)
+			written := filepath.Clean(e.Name) == w.file && (e.Has(fsnotify.Write) || e.Has(fsnotify.Create))
+			if written || (currentFile != "" && currentFile != realFile) {
+				realFile = currentFile
+				w.reload()
+			}
+		case err, ok := <-w.fsWatcher.Errors:
+			if !ok {
+				return
+			}
+
+			w.logger.Error("config watch error", err, "file", w.file)
+		}
+	}
+}
+
+// reload loads and validates the configuration again, and notifies the subscribers
+// once it is applied. Reloads run one at a time from the watch goroutine, and the
+// subscribers are called without holding the lock, so they may use the Watcher.
+func (w *Watcher) reload() {
+	w.mu.RLock()
+	prev := w.current
+	validators := slices.Clone(w.validators)
+	w.mu.RUnlock()
+
+	next, err := Load(prev.AppBuild, prev.opts...)
+	if err != nil {
+		w.logger.Error("config reload rejected", err, "file", w.file)
+		return
+	}
+
+	for _, validate := range validators {
+		if err := validate(next); err != nil {
+			w.logger.Error("config reload rejected", err, "file", w.file)
+			return
+		}
+	}
+
+	w.mu.Lock()
+	w.current = next
+	subs := slices.Clone(w.subs)
+	w.mu.Unlock()
+
+	w.logger.Info("config reloaded", "file", w.file)
+
+	for _, fn := range subs {
+		fn(prev, next)
+	}
+}
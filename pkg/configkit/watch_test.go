@@ -0,0 +1,116 @@
+package configkit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasay-usmani/go-boilerplate/pkg/logkit"
+)
+
+const _watchConfig = `
+ENVIRONMENT = "development"
+HTTP_LISTEN_HOST = "127.0.0.1"
+HTTP_LISTEN_PORT = "8080"
+RPC_LISTEN_PORT = "9090"
+DB_SCHEMA = "app"
+DB_SUPERUSER_URL = "root:root@tcp(db:3306)/mysql"
+DB_WRITE_URL = "app:app@tcp(db:3306)/app"
+DB_READ_URL = "app:app@tcp(db:3306)/app"
+`
+
+func TestWatch_NoConfigFile(t *testing.T) {
+	t.Setenv("ENVIRONMENT", "development")
+	t.Setenv("LOG_LEVEL", "info")
+	t.Setenv("HTTP_LISTEN_HOST", "127.0.0.1")
+	t.Setenv("HTTP_LISTEN_PORT", "8080")
+	t.Setenv("RPC_LISTEN_PORT", "9090")
+	setDBEnv(t)
+
+	cfg, err := Load("build", WithAppName("configkit-test"), WithEnvFile(""))
+	require.NoError(t, err)
+
+	_, err = cfg.Watch(logkit.NewLogger(logkit.Info, "configkit-test", logkit.WithOutput(io.Discard)))
+	assert.ErrorIs(t, err, ErrNoConfigFile)
+}
+
+func TestWatch_ReloadAndReject(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "config.toml", _watchConfig+`LOG_LEVEL = "info"`)
+
+	cfg, err := Load("build", WithAppName("configkit-test"), WithConfigPaths(dir), WithEnvFile(""))
+	require.NoError(t, err)
+
+	w, err := cfg.Watch(logkit.NewLogger(logkit.Info, "configkit-test", logkit.WithOutput(io.Discard)))
+	require.NoError(t, err)
+	defer w.Close()
+
+	changes := make(chan string, 10)
+	w.AddValidator(func(next *C) error {
+		if next.Log.Level == "fatal" {
+			return errors.New("fatal level not allowed")
+		}
+
+		return nil
+	})
+	// Subscribers may read the watcher they are notified by
+	w.Subscribe(func(_, _ *C) {
+		changes <- w.Current().Log.Level
+	})
+
+	// Accepted change
+	writeFile(t, dir, "config.toml", _watchConfig+`LOG_LEVEL = "debug"`)
+	select {
+	case level := <-changes:
+		assert.Equal(t, "debug", level)
+	case <-time.After(5 * time.Second):
+		t.Fatal("config change was not observed")
+	}
+
+	assert.Equal(t, "debug", w.Current().Log.Level)
+
+	// Rejected by struct validation, then by the custom validator
+	writeFile(t, dir, "config.toml", _watchConfig+`LOG_LEVEL = "verbose"`)
+	writeFile(t, dir, "config.toml", _watchConfig+`LOG_LEVEL = "fatal"`)
+	select {
+	case level := <-changes:
+		t.Fatalf("invalid config change was applied: %s", level)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	assert.Equal(t, "debug", w.Current().Log.Level)
+}
+
+func TestWatch_RunStopsOnContextDone(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "config.toml", _watchConfig+`LOG_LEVEL = "info"`)
+
+	cfg, err := Load("build", WithAppName("configkit-test"), WithConfigPaths(dir), WithEnvFile(""))
+	require.NoError(t, err)
+
+	w, err := cfg.Watch(logkit.NewLogger(logkit.Info, "configkit-test", logkit.WithOutput(io.Discard)))
+	require.NoError(t, err)
+
+	changes := make(chan string, 10)
+	w.Subscribe(func(_, next *C) {
+		changes <- next.Log.Level
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, w.Run(ctx))
+	require.NoError(t, w.Close())
+
+	writeFile(t, dir, "config.toml", _watchConfig+`LOG_LEVEL = "debug"`)
+	select {
+	case level := <-changes:
+		t.Fatalf("config change was applied after the watch stopped: %s", level)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	assert.Equal(t, "info", w.Current().Log.Level)
+}
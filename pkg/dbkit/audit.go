@@ -0,0 +1,173 @@
+package dbkit
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/wasay-usmani/go-boilerplate/pkg/timex"
+)
+
+// Audit and soft delete columns every app table carries
+const (
+	CreatedAtColumn = "created_at"
+	UpdatedAtColumn = "updated_at"
+	CreatedByColumn = "created_by"
+	UpdatedByColumn = "updated_by"
+	DeletedAtColumn = "deleted_at"
+
+	_notDeleted = DeletedAtColumn + " IS NULL"
+)
+
+type actorCtxKey struct{}
+
+// ScopeOption changes the conditions added by the query helpers, ListQuery.SQL,
+// Versioned.Update and TenantScope.
+type ScopeOption func(*scopeOptions)
+
+type scopeOptions struct {
+	withDeleted bool
+}
+
+// WithDeleted includes the soft deleted rows the query helpers exclude by default
+// with deleted_at IS NULL. Pass it for tables without a deleted_at column too.
+func WithDeleted() ScopeOption {
+	return func(o *scopeOptions) {
+		o.withDeleted = true
+	}
+}
+
+func newScopeOptions(opts []ScopeOption) scopeOptions {
+	var o scopeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// WithActor returns a copy of ctx carrying the actor of the request, e.g. the ID of
+// the authenticated user, recorded in the created_by and updated_by columns.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorCtxKey{}, actor)
+}
+
+// ActorFromContext returns the actor carried by ctx, see WithActor.
+func ActorFromContext(ctx context.Context) (string, bool) {
+	actor, ok := ctx.Value(actorCtxKey{}).(string)
+	return actor, ok && actor != ""
+}
+
+// AuditInsertHook stamps the created_at and updated_at columns of a sqlboiler model
+// with timex.Now, and its created_by and updated_by columns with the actor of ctx.
+// Register it, and AuditUpdateHook, on every generated model with audit columns:
+//
+//	models.AddUserHook(boil.BeforeInsertHook, dbkit.AuditInsertHook[*models.User])
+//	models.AddUserHook(boil.BeforeUpsertHook, dbkit.AuditInsertHook[*models.User])
+//	models.AddUserHook(boil.BeforeUpdateHook, dbkit.AuditUpdateHook[*models.User])
+//
+// Columns are found by their boil struct tag, the time columns may be time.Time or a
+// nullable time, the actor columns a string or a nullable string.
+func AuditInsertHook[M any](ctx context.Context, _ boil.ContextExecutor, m M) error {
+	stampAudit(ctx, m, CreatedAtColumn, UpdatedAtColumn, CreatedByColumn, UpdatedByColumn)
+	return nil
+}
+
+// AuditUpdateHook stamps the updated_at and updated_by columns of a sqlboiler model,
+// see AuditInsertHook. Repository.Update saves them along with the given columns.
+func AuditUpdateHook[M any](ctx context.Context, _ boil.ContextExecutor, m M) error {
+	stampAudit(ctx, m, UpdatedAtColumn, UpdatedByColumn)
+	return nil
+}
+
+// stampAudit sets the given audit columns of m, timestamps unless skipped with
+// boil.SkipTimestamps and actors only when ctx carries one.
+func stampAudit(ctx context.Context, m any, columns ...string) {
+	now := timex.Now()
+	actor, hasActor := ActorFromContext(ctx)
+	fields := modelFields(m)
+	for _, col := range columns {
+		field, ok := fields[col]
+		if !ok {
+			continue
+		}
+
+		switch col {
+		case CreatedAtColumn, UpdatedAtColumn:
+			if !boil.TimestampsAreSkipped(ctx) {
+				setField(field, reflect.ValueOf(now))
+			}
+		case CreatedByColumn, UpdatedByColumn:
+			if hasActor {
+				setField(field, reflect.ValueOf(actor))
+			}
+		}
+	}
+}
+
+// auditColumns returns the update audit columns m has that are missing from columns,
+// so a whitelisted update saves what AuditUpdateHook stamped.
+func auditColumns(m any, columns []string) []string {
+	fields := modelFields(m)
+	var missing []string
+	for _, col := range []string{UpdatedAtColumn, UpdatedByColumn} {
+		if _, ok := fields[col]; ok && !slices.Contains(columns, col) {
+			missing = append(missing, col)
+		}
+	}
+
+	return missing
+}
+
+// modelFields returns the settable fields of the struct m points to by column name.
+func modelFields(m any) map[string]reflect.Value {
+	v := reflect.ValueOf(m)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	v = v.Elem()
+	fields := make(map[string]reflect.Value, v.NumField())
+	for i := range v.NumField() {
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("boil"), ",")
+		if name != "" && name != "-" && v.Field(i).CanSet() {
+			fields[name] = v.Field(i)
+		}
+	}
+
+	return fields
+}
+
+// setField sets field to value, directly, through a pointer or through the Valid
+// and value fields of nullable types such as null.Time or sql.NullString.
+func setField(field, value reflect.Value) {
+	switch {
+	case value.Type().AssignableTo(field.Type()):
+		field.Set(value)
+	case field.Kind() == reflect.Pointer && value.Type().AssignableTo(field.Type().Elem()):
+		ptr := reflect.New(value.Type())
+		ptr.Elem().Set(value)
+		field.Set(ptr)
+	case field.Kind() == reflect.Struct:
+		valid := field.FieldByName("Valid")
+		inner := nullableValue(field, value.Type())
+		if valid.IsValid() && valid.Kind() == reflect.Bool && inner.IsValid() {
+			inner.Set(value)
+			valid.SetBool(true)
+		}
+	}
+}
+
+// nullableValue returns the value field of a nullable struct, e.g. Time of null.Time.
+func nullableValue(field reflect.Value, typ reflect.Type) reflect.Value {
+	for _, name := range []string{"Time", "String"} {
+		inner := field.FieldByName(name)
+		if inner.IsValid() && inner.Type() == typ && inner.CanSet() {
+			return inner
+		}
+	}
+
+	return reflect.Value{}
+}
@@ -0,0 +1,98 @@
+package dbkit
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// auditedThing mimics a sqlboiler model with audit and soft delete columns.
+type auditedThing struct {
+	ID        int            `boil:"id" json:"id"`
+	Name      string         `boil:"name" json:"name"`
+	CreatedAt time.Time      `boil:"created_at" json:"created_at"`
+	UpdatedAt sql.NullTime   `boil:"updated_at" json:"updated_at,omitempty"`
+	CreatedBy *string        `boil:"created_by" json:"created_by,omitempty"`
+	UpdatedBy sql.NullString `boil:"updated_by" json:"updated_by,omitempty"`
+	DeletedAt sql.NullTime   `boil:"deleted_at" json:"deleted_at,omitempty"`
+}
+
+func (t *auditedThing) Insert(context.Context, boil.ContextExecutor, boil.Columns) error {
+	return nil
+}
+
+func (t *auditedThing) Update(ctx context.Context, exec boil.ContextExecutor, columns boil.Columns) (int64, error) {
+	res, err := exec.ExecContext(ctx, "UPDATE `things` SET "+strings.Join(columns.Cols, ", ")+" WHERE `id` = ?", t.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (t *auditedThing) Delete(ctx context.Context, exec boil.ContextExecutor, hardDelete bool) (int64, error) {
+	query := "UPDATE `things` SET `deleted_at` = ? WHERE `id` = ?"
+	args := []any{time.Now(), t.ID}
+	if hardDelete {
+		query, args = "DELETE FROM `things` WHERE `id` = ?", args[1:]
+	}
+
+	res, err := exec.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func TestAuditHooks(t *testing.T) {
+	ctx := WithActor(context.Background(), "user-1")
+	th := &auditedThing{}
+	require.NoError(t, AuditInsertHook(ctx, nil, th))
+	assert.False(t, th.CreatedAt.IsZero())
+	assert.Equal(t, time.UTC, th.CreatedAt.Location())
+	assert.True(t, th.UpdatedAt.Valid)
+	require.NotNil(t, th.CreatedBy)
+	assert.Equal(t, "user-1", *th.CreatedBy)
+	assert.Equal(t, sql.NullString{String: "user-1", Valid: true}, th.UpdatedBy)
+
+	createdAt := th.CreatedAt
+	ctx = boil.SkipTimestamps(WithActor(context.Background(), "user-2"))
+	require.NoError(t, AuditUpdateHook(ctx, nil, th))
+	assert.Equal(t, createdAt, th.CreatedAt)
+	assert.Equal(t, createdAt, th.UpdatedAt.Time)
+	assert.Equal(t, "user-1", *th.CreatedBy)
+	assert.Equal(t, "user-2", th.UpdatedBy.String)
+
+	_, ok := ActorFromContext(context.Background())
+	assert.False(t, ok)
+	assert.NoError(t, AuditInsertHook(context.Background(), nil, &thing{}))
+}
+
+func TestRepository_AuditedUpdateAndSoftDelete(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	defer db.Close()
+
+	repo := NewRepository[*auditedThing, []*auditedThing, Query[*auditedThing, []*auditedThing]](db, nil, "id")
+	mock.ExpectExec("UPDATE `things` SET name, updated_at, updated_by WHERE `id` = \\?").WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `things` SET `deleted_at` = \\? WHERE `id` = \\?").WithArgs(sqlmock.AnyArg(), 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM `things` WHERE `id` = \\?").WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	th := &auditedThing{ID: 3}
+	columns := []string{"name"}
+	require.NoError(t, repo.Update(context.Background(), th, columns...))
+	assert.Equal(t, []string{"name"}, columns)
+	require.NoError(t, repo.Delete(context.Background(), th))
+	require.NoError(t, repo.HardDelete(context.Background(), th))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,313 @@
+package dbkit
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
+	"github.com/wasay-usmani/go-boilerplate/pkg/errorx"
+)
+
+const (
+	// MinCursorKeyLength is the minimum length of the HMAC key of a CursorCodec, in bytes
+	MinCursorKeyLength = 32
+
+	_cursorSeparator = "."
+	// _keysetHashLength is the number of bytes of the keyset hash kept in cursors
+	_keysetHashLength = 12
+)
+
+var (
+	// ErrInvalidCursor is wrapped by the errorx.BadRequest returned for malformed or tampered cursors.
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrCursorKeyTooShort is returned by NewCursorCodec for keys shorter than MinCursorKeyLength.
+	ErrCursorKeyTooShort = fmt.Errorf("cursor key must be at least %d bytes", MinCursorKeyLength)
+)
+
+// Cursor is the position of a row in a keyset paginated list: the values of its
+// sort columns, the tie-breaking column last. Backward cursors point to the page
+// before the row. Cursors leave the service as opaque, signed strings bound to the
+// Keyset of their list, see CursorCodec.
+//
+// Values are time.Time, *time.Time, numbers or strings. Times decode back to
+// time.Time, in UTC, so the driver binds them as timestamps. Other values decode
+// to JSON numbers and strings, the database converts them back when they are
+// compared to the sort columns. Pass the Time of nullable times, e.g. null.Time.
+type Cursor struct {
+	Values   []any
+	Backward bool
+}
+
+// cursorJSON is the signed payload of a Cursor. Keyset is the hash of the Keyset
+// the cursor was encoded for.
+type cursorJSON struct {
+	Keyset   string            `json:"k"`
+	Values   []json.RawMessage `json:"v"`
+	Backward bool              `json:"b,omitempty"`
+}
+
+// cursorTime is the payload form of time values, so they are not mistaken for strings.
+type cursorTime struct {
+	Time time.Time `json:"t"`
+}
+
+// CursorCodec signs cursors so clients can not forge positions.
+type CursorCodec struct {
+	key []byte
+}
+
+// Keyset describes a keyset paginated list. Name identifies the list, e.g. its
+// table, so cursors of lists sharing the same columns are not interchangeable.
+// Columns are the sort columns, the last one must be unique (e.g. the primary key)
+// to break ties. Columns are interpolated in the query and must come from code,
+// never from user input.
+type Keyset struct {
+	Name      string
+	Columns   []string
+	Direction string
+	Limit     int
+}
+
+// Page is a page of a keyset paginated list, with the cursors of its neighbour pages.
+// Empty cursors mean there is no next or previous page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// NewCursorCodec returns a codec signing cursors with key, a secret of at least
+// MinCursorKeyLength random bytes shared by every replica of the service.
+func NewCursorCodec(key []byte) (*CursorCodec, error) {
+	if len(key) < MinCursorKeyLength {
+		return nil, ErrCursorKeyTooShort
+	}
+
+	return &CursorCodec{key: slices.Clone(key)}, nil
+}
+
+// Encode returns the opaque, signed string form of c, a position in the list of k.
+func (cc *CursorCodec) Encode(k Keyset, c Cursor) (string, error) {
+	payload, err := c.marshal(k)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + _cursorSeparator + enc.EncodeToString(cc.sign(payload)), nil
+}
+
+// Decode verifies and decodes a cursor returned by Encode for k. An empty string
+// decodes to a nil cursor, the first page. Invalid cursors, and cursors of another
+// list or sort order, return an errorx.BadRequest.
+func (cc *CursorCodec) Decode(k Keyset, s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	enc := base64.RawURLEncoding
+	encPayload, encSig, ok := strings.Cut(s, _cursorSeparator)
+	if !ok {
+		return nil, invalidCursor()
+	}
+
+	payload, err := enc.DecodeString(encPayload)
+	if err != nil {
+		return nil, invalidCursor()
+	}
+
+	sig, err := enc.DecodeString(encSig)
+	if err != nil || !hmac.Equal(sig, cc.sign(payload)) {
+		return nil, invalidCursor()
+	}
+
+	c, err := unmarshalCursor(k, payload)
+	if err != nil || len(c.Values) == 0 {
+		return nil, invalidCursor()
+	}
+
+	return c, nil
+}
+
+func (c Cursor) marshal(k Keyset) ([]byte, error) {
+	out := cursorJSON{Keyset: k.hash(), Values: make([]json.RawMessage, len(c.Values)), Backward: c.Backward}
+	for i, v := range c.Values {
+		switch t := v.(type) {
+		case time.Time:
+			v = cursorTime{Time: t.UTC()}
+		case *time.Time:
+			if t != nil {
+				v = cursorTime{Time: t.UTC()}
+			}
+		}
+
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+
+		out.Values[i] = raw
+	}
+
+	return json.Marshal(out)
+}
+
+func unmarshalCursor(k Keyset, payload []byte) (*Cursor, error) {
+	var in cursorJSON
+	if err := json.Unmarshal(payload, &in); err != nil {
+		return nil, err
+	}
+
+	if in.Keyset != k.hash() {
+		return nil, ErrInvalidCursor
+	}
+
+	c := &Cursor{Values: make([]any, len(in.Values)), Backward: in.Backward}
+	for i, raw := range in.Values {
+		if bytes.HasPrefix(raw, []byte("{")) {
+			var t cursorTime
+			if err := json.Unmarshal(raw, &t); err != nil {
+				return nil, err
+			}
+
+			c.Values[i] = t.Time
+			continue
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&c.Values[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+func (cc *CursorCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, cc.key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// QueryMods returns the where, order by and limit query mods of the page at cursor,
+// the first page when cursor is nil. One extra row is fetched to detect a following
+// page, pass the rows as is to NewPage.
+func (k Keyset) QueryMods(cursor *Cursor) ([]qm.QueryMod, error) {
+	dir := k.direction()
+	backward := cursor != nil && cursor.Backward
+	if backward {
+		dir = reverse(dir)
+	}
+
+	order := make([]string, len(k.Columns))
+	for i, col := range k.Columns {
+		order[i] = col + " " + dir
+	}
+
+	mods := []qm.QueryMod{
+		qm.OrderBy(strings.Join(order, ", ")),
+		qm.Limit(k.limit() + 1),
+	}
+
+	if cursor == nil {
+		return mods, nil
+	}
+
+	if len(cursor.Values) != len(k.Columns) {
+		return nil, invalidCursor()
+	}
+
+	op := ">"
+	if dir == DirectionDESC {
+		op = "<"
+	}
+
+	where := fmt.Sprintf("(%s) %s (%s)", strings.Join(k.Columns, ", "), op, placeholders(len(k.Columns)))
+	return append([]qm.QueryMod{qm.Where(where, cursor.Values...)}, mods...), nil
+}
+
+func (k Keyset) direction() string {
+	return PrimaryOrDefaultDirection(strings.ToUpper(k.Direction))
+}
+
+// hash identifies the list and sort order of k in its cursors. The limit is left
+// out, clients may change the page size between pages.
+func (k Keyset) hash() string {
+	h := sha256.New()
+	for _, part := range append([]string{k.Name, k.direction()}, k.Columns...) {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:_keysetHashLength])
+}
+
+func (k Keyset) limit() int {
+	if k.Limit > 0 {
+		return k.Limit
+	}
+
+	return _defaultLimit
+}
+
+// NewPage builds the page from the rows fetched with the QueryMods of cursor.
+// values returns the sort column values of a row, in Keyset.Columns order.
+func NewPage[T any](k Keyset, cc *CursorCodec, cursor *Cursor, rows []T, values func(T) []any) (Page[T], error) {
+	backward := cursor != nil && cursor.Backward
+	hasMore := len(rows) > k.limit()
+	if hasMore {
+		rows = rows[:k.limit()]
+	}
+
+	// Backward pages are fetched in reverse order
+	if backward {
+		rows = slices.Clone(rows)
+		slices.Reverse(rows)
+	}
+
+	page := Page[T]{Items: rows}
+	if len(rows) == 0 {
+		return page, nil
+	}
+
+	hasNext, hasPrev := hasMore, cursor != nil
+	if backward {
+		hasNext, hasPrev = true, hasMore
+	}
+
+	var err error
+	if hasNext {
+		if page.NextCursor, err = cc.Encode(k, Cursor{Values: values(rows[len(rows)-1])}); err != nil {
+			return Page[T]{}, err
+		}
+	}
+
+	if hasPrev {
+		if page.PrevCursor, err = cc.Encode(k, Cursor{Values: values(rows[0]), Backward: true}); err != nil {
+			return Page[T]{}, err
+		}
+	}
+
+	return page, nil
+}
+
+func reverse(direction string) string {
+	if direction == DirectionASC {
+		return DirectionDESC
+	}
+
+	return DirectionASC
+}
+
+func invalidCursor() error {
+	return errorx.New(errorx.BadRequest, "invalid cursor", errorx.WithCause(ErrInvalidCursor))
+}
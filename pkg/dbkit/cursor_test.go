@@ -0,0 +1,203 @@
+package dbkit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/drivers"
+	"github.com/aarondl/sqlboiler/v4/queries"
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasay-usmani/go-boilerplate/pkg/errorx"
+)
+
+var _testCursorKey = []byte("0123456789abcdef0123456789abcdef")
+
+type testRow struct {
+	CreatedAt string
+	ID        int
+}
+
+func buildQuery(t *testing.T, mods []qm.QueryMod) (string, []any) {
+	t.Helper()
+
+	q := &queries.Query{}
+	queries.SetDialect(q, &drivers.Dialect{LQ: '`', RQ: '`'})
+	queries.SetFrom(q, "`users`")
+	qm.Apply(q, mods...)
+
+	return queries.BuildQuery(q)
+}
+
+func newTestCursorCodec(t *testing.T, key []byte) *CursorCodec {
+	t.Helper()
+
+	cc, err := NewCursorCodec(key)
+	require.NoError(t, err)
+	return cc
+}
+
+func TestNewCursorCodec_ShortKey(t *testing.T) {
+	for _, key := range [][]byte{nil, {}, []byte("secret")} {
+		_, err := NewCursorCodec(key)
+		assert.ErrorIs(t, err, ErrCursorKeyTooShort)
+	}
+}
+
+var _testKeyset = Keyset{Name: "users", Columns: []string{"created_at", "id"}}
+
+func TestCursorCodec(t *testing.T) {
+	cc := newTestCursorCodec(t, _testCursorKey)
+	k := _testKeyset
+
+	token, err := cc.Encode(k, Cursor{Values: []any{"2024-01-02T03:04:05Z", 42}, Backward: true})
+	require.NoError(t, err)
+
+	cursor, err := cc.Decode(k, token)
+	require.NoError(t, err)
+	assert.Equal(t, []any{"2024-01-02T03:04:05Z", json.Number("42")}, cursor.Values)
+	assert.True(t, cursor.Backward)
+
+	first, err := cc.Decode(k, "")
+	require.NoError(t, err)
+	assert.Nil(t, first)
+
+	// Cursors signed with another key, or altered, are rejected
+	forged, err := newTestCursorCodec(t, []byte("fedcba9876543210fedcba9876543210")).Encode(k, Cursor{Values: []any{1}})
+	require.NoError(t, err)
+	for _, token := range []string{forged, "garbage", token[1:]} {
+		_, err := cc.Decode(k, token)
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+		assert.True(t, errorx.Is(err, errorx.BadRequest))
+	}
+}
+
+func TestCursorCodec_OtherKeyset(t *testing.T) {
+	cc := newTestCursorCodec(t, _testCursorKey)
+	k := Keyset{Name: "users", Columns: []string{"name", "id"}, Limit: 10}
+
+	token, err := cc.Encode(k, Cursor{Values: []any{"alice", 1}})
+	require.NoError(t, err)
+
+	// The page size is not part of the list, it may change between pages
+	_, err = cc.Decode(Keyset{Name: "users", Columns: []string{"name", "id"}, Direction: "desc", Limit: 50}, token)
+	require.NoError(t, err)
+
+	for _, other := range []Keyset{
+		{Name: "users", Columns: []string{"created_at", "id"}},
+		{Name: "users", Columns: []string{"name", "id"}, Direction: "asc"},
+		{Name: "groups", Columns: []string{"name", "id"}},
+	} {
+		_, err := cc.Decode(other, token)
+		assert.ErrorIs(t, err, ErrInvalidCursor, other)
+		assert.True(t, errorx.Is(err, errorx.BadRequest))
+	}
+}
+
+func TestCursorCodec_Times(t *testing.T) {
+	cc := newTestCursorCodec(t, _testCursorKey)
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 123456000, time.FixedZone("CET", 3600))
+
+	token, err := cc.Encode(_testKeyset, Cursor{Values: []any{createdAt, &createdAt, "2024-01-02T03:04:05Z", 42}})
+	require.NoError(t, err)
+
+	cursor, err := cc.Decode(_testKeyset, token)
+	require.NoError(t, err)
+	require.Len(t, cursor.Values, 4)
+	for _, v := range cursor.Values[:2] {
+		decoded, ok := v.(time.Time)
+		require.True(t, ok, "time values decode to time.Time, got %T", v)
+		assert.True(t, createdAt.Equal(decoded))
+		assert.Equal(t, time.UTC, decoded.Location())
+	}
+
+	// Strings looking like times are left as strings
+	assert.Equal(t, "2024-01-02T03:04:05Z", cursor.Values[2])
+	assert.Equal(t, json.Number("42"), cursor.Values[3])
+}
+
+func TestKeyset_QueryMods(t *testing.T) {
+	k := Keyset{Columns: []string{"created_at", "id"}, Direction: "desc", Limit: 2}
+
+	mods, err := k.QueryMods(nil)
+	require.NoError(t, err)
+	query, args := buildQuery(t, mods)
+	assert.Equal(t, "SELECT * FROM `users` ORDER BY created_at DESC, id DESC LIMIT 3;", query)
+	assert.Empty(t, args)
+
+	mods, err = k.QueryMods(&Cursor{Values: []any{"2024-01-02", 7}})
+	require.NoError(t, err)
+	query, args = buildQuery(t, mods)
+	assert.Equal(t, "SELECT * FROM `users` WHERE ((created_at, id) < (?, ?)) ORDER BY created_at DESC, id DESC LIMIT 3;", query)
+	assert.Equal(t, []any{"2024-01-02", 7}, args)
+
+	mods, err = k.QueryMods(&Cursor{Values: []any{"2024-01-02", 7}, Backward: true})
+	require.NoError(t, err)
+	query, _ = buildQuery(t, mods)
+	assert.Equal(t, "SELECT * FROM `users` WHERE ((created_at, id) > (?, ?)) ORDER BY created_at ASC, id ASC LIMIT 3;", query)
+
+	_, err = k.QueryMods(&Cursor{Values: []any{7}})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestNewPage(t *testing.T) {
+	cc := newTestCursorCodec(t, _testCursorKey)
+	k := Keyset{Columns: []string{"created_at", "id"}, Limit: 2}
+	values := func(r testRow) []any { return []any{r.CreatedAt, r.ID} }
+	decode := func(token string) *Cursor {
+		c, err := cc.Decode(k, token)
+		require.NoError(t, err)
+		return c
+	}
+
+	// First page, one extra row fetched: there is a next page only
+	page, err := NewPage(k, cc, nil, []testRow{{"c", 3}, {"b", 2}, {"a", 1}}, values)
+	require.NoError(t, err)
+	assert.Equal(t, []testRow{{"c", 3}, {"b", 2}}, page.Items)
+	assert.Empty(t, page.PrevCursor)
+	next := decode(page.NextCursor)
+	assert.Equal(t, []any{"b", json.Number("2")}, next.Values)
+	assert.False(t, next.Backward)
+
+	// Last page reached from a cursor: there is a previous page only
+	page, err = NewPage(k, cc, next, []testRow{{"a", 1}}, values)
+	require.NoError(t, err)
+	assert.Empty(t, page.NextCursor)
+	prev := decode(page.PrevCursor)
+	assert.Equal(t, []any{"a", json.Number("1")}, prev.Values)
+	assert.True(t, prev.Backward)
+
+	// Backward page rows come in reverse order and are restored
+	page, err = NewPage(k, cc, prev, []testRow{{"b", 2}, {"c", 3}}, values)
+	require.NoError(t, err)
+	assert.Equal(t, []testRow{{"c", 3}, {"b", 2}}, page.Items)
+	assert.NotEmpty(t, page.NextCursor)
+	assert.Empty(t, page.PrevCursor)
+}
+
+func TestNewPage_TimeColumn(t *testing.T) {
+	type event struct {
+		CreatedAt time.Time
+		ID        int
+	}
+
+	cc := newTestCursorCodec(t, _testCursorKey)
+	k := Keyset{Columns: []string{"created_at", "id"}, Direction: "desc", Limit: 1}
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	values := func(e event) []any { return []any{e.CreatedAt, e.ID} }
+
+	page, err := NewPage(k, cc, nil, []event{{createdAt, 2}, {createdAt.Add(-time.Hour), 1}}, values)
+	require.NoError(t, err)
+
+	next, err := cc.Decode(k, page.NextCursor)
+	require.NoError(t, err)
+
+	// The decoded time is bound as a time.Time, not as a string the database has to parse
+	mods, err := k.QueryMods(next)
+	require.NoError(t, err)
+	query, args := buildQuery(t, mods)
+	assert.Equal(t, "SELECT * FROM `users` WHERE ((created_at, id) < (?, ?)) ORDER BY created_at DESC, id DESC LIMIT 2;", query)
+	assert.Equal(t, []any{createdAt, json.Number("2")}, args)
+}
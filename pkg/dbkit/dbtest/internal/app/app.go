@@ -0,0 +1,8 @@
+package app
+
+import "embed"
+
+// Assets holds the app migrations of the dbtest tests
+//
+//go:embed migrations/*.sql
+var Assets embed.FS
@@ -0,0 +1,8 @@
+-- +migrate Up
+CREATE TABLE widgets (
+    id INT NOT NULL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL
+);
+
+-- +migrate Down
+DROP TABLE widgets;
@@ -0,0 +1,4 @@
+-- +migrate Up
+-- The test schema is created by dbtest.New, the migrator only records this migration
+
+-- +migrate Down
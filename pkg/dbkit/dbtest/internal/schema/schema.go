@@ -0,0 +1,8 @@
+package schema
+
+import "embed"
+
+// Assets holds the schema migrations of the dbtest tests
+//
+//go:embed migrations/*.sql
+var Assets embed.FS
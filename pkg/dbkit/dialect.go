@@ -0,0 +1,102 @@
+package dbkit
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/wasay-usmani/go-boilerplate/pkg/logkit"
+)
+
+// Dialect is the SQL dialect of a database connection.
+type Dialect string
+
+const (
+	MySQL    Dialect = "mysql"
+	Postgres Dialect = "postgres"
+)
+
+// DialectFromURL detects the dialect of a connection string. Postgres URLs
+// (postgres:// or postgresql://) are Postgres, then a valid MySQL DSN is MySQL,
+// whatever its parameters or password hold, and a valid key=value Postgres DSN is
+// Postgres. Anything else is expected to be MySQL, failing on connect. Set the
+// dialect explicitly, e.g. with DB_DIALECT, when a DSN is valid in both formats.
+func DialectFromURL(dbURL string) Dialect {
+	dbURL = strings.TrimSpace(dbURL)
+	lower := strings.ToLower(dbURL)
+	if strings.HasPrefix(lower, "postgres://") || strings.HasPrefix(lower, "postgresql://") {
+		return Postgres
+	}
+
+	if _, err := mysql.ParseDSN(dbURL); err == nil {
+		return MySQL
+	}
+
+	if _, err := pq.NewConnector(dbURL); err == nil {
+		return Postgres
+	}
+
+	return MySQL
+}
+
+// LoadSQLConn opens a connection with the driver matching the dialect of dbURL.
+func LoadSQLConn(ctx context.Context, dbURL string, debug bool, l logkit.Logger, opts ...Option) (*SQLConn, error) {
+	if DialectFromURL(dbURL) == Postgres {
+		return LoadPostgresSQLConn(ctx, dbURL, debug, l, opts...)
+	}
+
+	return LoadMySQLConn(ctx, dbURL, debug, l, opts...)
+}
+
+// LoadSchemaConn opens a connection with the driver of d, detected from dbURL when
+// empty, working in schema: on Postgres every pooled connection resolves unqualified
+// names in schema, see SearchPathURL, on MySQL schema is the database of dbURL.
+func LoadSchemaConn(
+	ctx context.Context, dbURL string, d Dialect, schema string, debug bool, l logkit.Logger, opts ...Option,
+) (*SQLConn, error) {
+	if d == "" {
+		d = DialectFromURL(dbURL)
+	}
+
+	if d != Postgres {
+		return LoadMySQLConn(ctx, dbURL, debug, l, opts...)
+	}
+
+	dbURL, err := SearchPathURL(dbURL, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadPostgresSQLConn(ctx, dbURL, debug, l, opts...)
+}
+
+// QuoteIdentifier quotes a table, schema or database name for d.
+func QuoteIdentifier(d Dialect, name string) string {
+	if d == Postgres {
+		return pq.QuoteIdentifier(name)
+	}
+
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// SearchPathURL sets the search_path run-time parameter of a Postgres connection
+// string, so every pooled connection resolves unqualified names in schema.
+func SearchPathURL(dbURL, schema string) (string, error) {
+	if !strings.Contains(dbURL, "://") {
+		return fmt.Sprintf("%s search_path=%s", dbURL, schema), nil
+	}
+
+	u, err := url.Parse(dbURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid postgres url: %w", err)
+	}
+
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
@@ -0,0 +1,165 @@
+package dbkit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/wasay-usmani/go-boilerplate/pkg/errorx"
+)
+
+const (
+	_mysqlBadNull         = 1048
+	_mysqlDupEntry        = 1062
+	_mysqlRowIsReferenced = 1451
+	_mysqlNoReferencedRow = 1452
+	_mysqlCheckViolated   = 3819
+
+	_pgNotNullViolation    = "23502"
+	_pgForeignKeyViolation = "23503"
+	_pgUniqueViolation     = "23505"
+	_pgCheckViolation      = "23514"
+	_pgQueryCanceled       = "57014"
+)
+
+var (
+	// e.g. Duplicate entry 'a@b.c' for key 'users.users_email_key'
+	_mysqlKeyRe = regexp.MustCompile(`for key '([^']+)'`)
+	// e.g. ... CONSTRAINT `orders_user_id_fkey` FOREIGN KEY ... or Check constraint 'positive_amount' is violated.
+	_mysqlConstraintRe = regexp.MustCompile("(?i)constraint [`']([^`']+)[`']")
+	// e.g. Column 'email' cannot be null
+	_mysqlColumnRe = regexp.MustCompile(`Column '([^']+)'`)
+)
+
+// MapError translates database driver errors into *errorx.Error, so callers do not
+// depend on driver specific error types:
+//
+//	sql.ErrNoRows                   errorx.NotFound
+//	unique violation                errorx.AlreadyExists
+//	missing referenced row          errorx.BadRequest
+//	row still referenced            errorx.Conflict
+//	check and not null violation    errorx.BadRequest
+//	deadline exceeded, pg timeouts  errorx.Timeout
+//
+// The constraint, and when known the table or column, are set in Fields and the
+// mapped error unwraps to err. Other errors are returned unchanged, nil stays nil.
+func MapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return errorx.New(errorx.NotFound, "record not found", errorx.WithCause(err))
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return errorx.New(errorx.Timeout, "database operation timed out", errorx.WithCause(err))
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mapMySQLError(err, mysqlErr)
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return mapPostgresError(err, pqErr)
+	}
+
+	return err
+}
+
+func mapMySQLError(err error, mysqlErr *mysql.MySQLError) error {
+	var code errorx.ErrorCode
+	var message string
+	fields := map[string]any{}
+	switch mysqlErr.Number {
+	case _mysqlDupEntry:
+		code, message = errorx.AlreadyExists, "record already exists"
+		fields["constraint"] = submatch(_mysqlKeyRe, mysqlErr.Message)
+	case _mysqlNoReferencedRow:
+		code, message = errorx.BadRequest, "referenced record does not exist"
+		fields["constraint"] = submatch(_mysqlConstraintRe, mysqlErr.Message)
+	case _mysqlRowIsReferenced:
+		code, message = errorx.Conflict, "record is still referenced"
+		fields["constraint"] = submatch(_mysqlConstraintRe, mysqlErr.Message)
+	case _mysqlCheckViolated:
+		code, message = errorx.BadRequest, "constraint violated"
+		fields["constraint"] = submatch(_mysqlConstraintRe, mysqlErr.Message)
+	case _mysqlBadNull:
+		code, message = errorx.BadRequest, "required value is missing"
+		fields["column"] = submatch(_mysqlColumnRe, mysqlErr.Message)
+	default:
+		return err
+	}
+
+	return errorx.New(code, message,
+		errorx.WithInternalCode(strconv.Itoa(int(mysqlErr.Number))),
+		errorx.WithFields(compact(fields)),
+		errorx.WithCause(err),
+	)
+}
+
+func mapPostgresError(err error, pqErr *pq.Error) error {
+	var code errorx.ErrorCode
+	var message string
+	switch pqErr.Code {
+	case _pgUniqueViolation:
+		code, message = errorx.AlreadyExists, "record already exists"
+	case _pgForeignKeyViolation:
+		// Deleting a referenced row is a conflict, inserting a row referencing a missing one a bad request
+		code, message = errorx.Conflict, "record is still referenced"
+		if strings.HasPrefix(pqErr.Message, "insert or update on table") {
+			code, message = errorx.BadRequest, "referenced record does not exist"
+		}
+	case _pgCheckViolation:
+		code, message = errorx.BadRequest, "constraint violated"
+	case _pgNotNullViolation:
+		code, message = errorx.BadRequest, "required value is missing"
+	case _pgQueryCanceled:
+		code, message = errorx.Timeout, "database operation timed out"
+	default:
+		return err
+	}
+
+	fields := compact(map[string]any{
+		"constraint": pqErr.Constraint,
+		"table":      pqErr.Table,
+		"column":     pqErr.Column,
+	})
+
+	return errorx.New(code, message,
+		errorx.WithInternalCode(string(pqErr.Code)),
+		errorx.WithFields(fields),
+		errorx.WithCause(err),
+	)
+}
+
+func submatch(re *regexp.Regexp, s string) string {
+	m := re.FindStringSubmatch(s)
+	if len(m) < 2 {
+		return ""
+	}
+
+	return m[1]
+}
+
+// compact drops empty values, returning nil when none is left.
+func compact(fields map[string]any) map[string]any {
+	for k, v := range fields {
+		if v == "" {
+			delete(fields, k)
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return fields
+}
@@ -0,0 +1,110 @@
+package dbkit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasay-usmani/go-boilerplate/pkg/errorx"
+)
+
+func TestMapError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		code   errorx.ErrorCode
+		fields map[string]any
+	}{
+		{
+			name: "no rows",
+			err:  fmt.Errorf("find user: %w", sql.ErrNoRows),
+			code: errorx.NotFound,
+		},
+		{
+			name: "deadline exceeded",
+			err:  context.DeadlineExceeded,
+			code: errorx.Timeout,
+		},
+		{
+			name:   "mysql duplicate entry",
+			err:    &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a@b.c' for key 'users.users_email_key'"},
+			code:   errorx.AlreadyExists,
+			fields: map[string]any{"constraint": "users.users_email_key"},
+		},
+		{
+			name: "mysql missing parent",
+			err: &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails " +
+				"(`app`.`orders`, CONSTRAINT `orders_user_id_fkey` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`))"},
+			code:   errorx.BadRequest,
+			fields: map[string]any{"constraint": "orders_user_id_fkey"},
+		},
+		{
+			name: "mysql parent still referenced",
+			err: &mysql.MySQLError{Number: 1451, Message: "Cannot delete or update a parent row: a foreign key constraint fails " +
+				"(`app`.`orders`, CONSTRAINT `orders_user_id_fkey` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`))"},
+			code:   errorx.Conflict,
+			fields: map[string]any{"constraint": "orders_user_id_fkey"},
+		},
+		{
+			name:   "mysql check violated",
+			err:    &mysql.MySQLError{Number: 3819, Message: "Check constraint 'positive_amount' is violated."},
+			code:   errorx.BadRequest,
+			fields: map[string]any{"constraint": "positive_amount"},
+		},
+		{
+			name:   "postgres unique violation",
+			err:    &pq.Error{Code: "23505", Constraint: "users_email_key", Table: "users"},
+			code:   errorx.AlreadyExists,
+			fields: map[string]any{"constraint": "users_email_key", "table": "users"},
+		},
+		{
+			name: "postgres missing parent",
+			err: &pq.Error{Code: "23503", Constraint: "orders_user_id_fkey", Table: "orders",
+				Message: `insert or update on table "orders" violates foreign key constraint "orders_user_id_fkey"`},
+			code:   errorx.BadRequest,
+			fields: map[string]any{"constraint": "orders_user_id_fkey", "table": "orders"},
+		},
+		{
+			name: "postgres parent still referenced",
+			err: &pq.Error{Code: "23503", Constraint: "orders_user_id_fkey", Table: "orders",
+				Message: `update or delete on table "users" violates foreign key constraint "orders_user_id_fkey" on table "orders"`},
+			code:   errorx.Conflict,
+			fields: map[string]any{"constraint": "orders_user_id_fkey", "table": "orders"},
+		},
+		{
+			name:   "postgres check violation",
+			err:    &pq.Error{Code: "23514", Constraint: "positive_amount"},
+			code:   errorx.BadRequest,
+			fields: map[string]any{"constraint": "positive_amount"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapped := MapError(tt.err)
+
+			var e *errorx.Error
+			require.ErrorAs(t, mapped, &e)
+			assert.Equal(t, tt.code, e.Code)
+			assert.Equal(t, tt.fields, e.Fields)
+			assert.ErrorIs(t, mapped, tt.err)
+			assert.True(t, errorx.Is(fmt.Errorf("repository: %w", mapped), tt.code))
+		})
+	}
+}
+
+func TestMapError_Passthrough(t *testing.T) {
+	assert.NoError(t, MapError(nil))
+
+	errOther := errors.New("boom")
+	assert.Same(t, errOther, MapError(errOther))
+
+	errSyntax := &mysql.MySQLError{Number: 1064, Message: "You have an error in your SQL syntax"}
+	assert.Same(t, errSyntax, MapError(errSyntax))
+}
@@ -2,6 +2,7 @@ package dbkit
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 )
 
@@ -17,6 +18,9 @@ const (
 	_defaultSortBy    = "created_at"
 )
 
+// _sortByRe matches plain, optionally table qualified, column names
+var _sortByRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
 func PrimaryOrDefaultOrderBy(primaryDirection, primarySortBy string) string {
 	direction := PrimaryOrDefaultDirection(primaryDirection)
 	sortBy := PrimaryOrDefaultSortBy(primarySortBy)
@@ -45,8 +49,11 @@ func PrimaryOrDefaultDirectionPtr(primary *string) string {
 	return _defaultDirection
 }
 
+// PrimaryOrDefaultSortBy returns primary when it is a plain column name, the default
+// sort column otherwise. It does not check the column exists, use a ListSpec to
+// whitelist the sortable columns of user input.
 func PrimaryOrDefaultSortBy(primary string) string {
-	if primary != "" {
+	if _sortByRe.MatchString(primary) {
 		return primary
 	}
 
@@ -54,8 +61,8 @@ func PrimaryOrDefaultSortBy(primary string) string {
 }
 
 func PrimaryOrDefaultSortByPtr(primary *string) string {
-	if primary != nil && *primary != "" {
-		return *primary
+	if primary != nil {
+		return PrimaryOrDefaultSortBy(*primary)
 	}
 
 	return _defaultSortBy
@@ -0,0 +1,325 @@
+package dbkit
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/wasay-usmani/go-boilerplate/pkg/logkit"
+)
+
+var (
+	// Literals embedded in raw queries, placeholders are left alone
+	_stringLiteralRe = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'`)
+	_numberLiteralRe = regexp.MustCompile(`(^|[^\w$])\d+(\.\d+)?`)
+	// The first table a query reads from or writes to
+	_queryTableRe = regexp.MustCompile("(?i)\\b(?:FROM|INTO|UPDATE|JOIN)\\s+([`\"\\w.]+)")
+)
+
+// QueryEvent describes a query run on an instrumented SQLConn.
+type QueryEvent struct {
+	// Operation is the lower case statement keyword, e.g. select or insert
+	Operation string
+	// Table is the first table of the query, empty when there is none
+	Table string
+	// Query is the query with its literals redacted, argument values are never included
+	Query    string
+	Args     int
+	Start    time.Time
+	Duration time.Duration
+	Err      error
+}
+
+// QueryObserver is notified of every query run on an instrumented SQLConn, inside
+// transactions too. It is called synchronously and must be safe for concurrent use.
+// TraceObserver attaches the queries to the OpenTelemetry span of ctx. Durations
+// of row returning queries stop at the first row.
+type QueryObserver interface {
+	ObserveQuery(ctx context.Context, e QueryEvent)
+}
+
+// QueryObserverFunc adapts a function to a QueryObserver.
+type QueryObserverFunc func(ctx context.Context, e QueryEvent)
+
+type instrumenter struct {
+	logger             logkit.Logger
+	slowQueryThreshold time.Duration
+	observers          []QueryObserver
+}
+
+type instrumentedConnector struct {
+	driver.Connector
+	inst *instrumenter
+}
+
+// dsnConnector is the connector of drivers not implementing driver.DriverContext.
+type dsnConnector struct {
+	dsn string
+	drv driver.Driver
+}
+
+type instrumentedConn struct {
+	driver.Conn
+	inst *instrumenter
+}
+
+type instrumentedStmt struct {
+	driver.Stmt
+	conn *instrumentedConn
+	text string
+}
+
+func (f QueryObserverFunc) ObserveQuery(ctx context.Context, e QueryEvent) {
+	f(ctx, e)
+}
+
+// WithSlowQueryThreshold logs the queries lasting at least d as warnings, 0 disables it.
+func WithSlowQueryThreshold(d time.Duration) Option {
+	return func(o *connOptions) {
+		o.slowQueryThreshold = d
+	}
+}
+
+// WithQueryObserver notifies the observers of every query, e.g. a QueryHistogram.
+func WithQueryObserver(observers ...QueryObserver) Option {
+	return func(o *connOptions) {
+		o.observers = append(o.observers, observers...)
+	}
+}
+
+// RedactQuery replaces the string and number literals of query with ?, so it can be logged.
+func RedactQuery(query string) string {
+	query = _stringLiteralRe.ReplaceAllString(query, "'?'")
+	return _numberLiteralRe.ReplaceAllString(query, "${1}?")
+}
+
+// classifyQuery returns the operation and first table of query.
+func classifyQuery(query string) (operation, table string) {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "", ""
+	}
+
+	operation = strings.ToLower(fields[0])
+	if m := _queryTableRe.FindStringSubmatch(query); m != nil {
+		table = strings.NewReplacer("`", "", `"`, "").Replace(m[1])
+	}
+
+	return operation, table
+}
+
+// openInstrumentedDB opens a pool whose connections time every query.
+func openInstrumentedDB(driverName, dbURL string, inst *instrumenter) (*sql.DB, error) {
+	// sql.Open does not connect, it is only used to look the driver up
+	db, err := sql.Open(driverName, dbURL)
+	if err != nil {
+		return nil, err
+	}
+
+	drv := db.Driver()
+	_ = db.Close()
+
+	var connector driver.Connector = dsnConnector{dsn: dbURL, drv: drv}
+	if dc, ok := drv.(driver.DriverContext); ok {
+		if connector, err = dc.OpenConnector(dbURL); err != nil {
+			return nil, err
+		}
+	}
+
+	return sql.OpenDB(&instrumentedConnector{Connector: connector, inst: inst}), nil
+}
+
+func (i *instrumenter) observe(ctx context.Context, query string, args int, start time.Time, err error) {
+	// The driver asked database/sql to prepare the query, the statement reports it
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+
+	e := QueryEvent{Query: RedactQuery(query), Args: args, Start: start, Duration: time.Since(start), Err: err}
+	e.Operation, e.Table = classifyQuery(query)
+	if i.slowQueryThreshold > 0 && e.Duration >= i.slowQueryThreshold && i.logger != nil {
+		i.logger.WithContext(ctx).Warn("slow query",
+			"query", e.Query, "args", e.Args, "operation", e.Operation, "table", e.Table,
+			"duration", e.Duration.String(), "threshold", i.slowQueryThreshold.String())
+	}
+
+	for _, o := range i.observers {
+		o.ObserveQuery(ctx, e)
+	}
+}
+
+func (c *instrumentedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &instrumentedConn{Conn: conn, inst: c.inst}, nil
+}
+
+func (c *instrumentedConnector) Close() error {
+	if closer, ok := c.Connector.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.drv.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.drv
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	start := time.Now()
+	res, err := execer.ExecContext(ctx, query, args)
+	c.inst.observe(ctx, query, len(args), start, err)
+	return res, err
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	c.inst.observe(ctx, query, len(args), start, err)
+	return rows, err
+}
+
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &instrumentedStmt{Stmt: stmt, conn: c, text: query}, nil
+}
+
+func (c *instrumentedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+
+	return c.Conn.Begin() //nolint:staticcheck // fallback of drivers without BeginTx
+}
+
+func (c *instrumentedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+
+	return nil
+}
+
+func (c *instrumentedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+
+	return nil
+}
+
+func (c *instrumentedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+
+	return true
+}
+
+func (c *instrumentedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+
+	return driver.ErrSkip
+}
+
+func (s *instrumentedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	res, err := s.exec(ctx, args)
+	s.conn.inst.observe(ctx, s.text, len(args), start, err)
+	return res, err
+}
+
+func (s *instrumentedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := s.query(ctx, args)
+	s.conn.inst.observe(ctx, s.text, len(args), start, err)
+	return rows, err
+}
+
+func (s *instrumentedStmt) exec(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		return execer.ExecContext(ctx, args)
+	}
+
+	values, err := namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Stmt.Exec(values) //nolint:staticcheck // fallback of drivers without ExecContext
+}
+
+func (s *instrumentedStmt) query(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		return queryer.QueryContext(ctx, args)
+	}
+
+	values, err := namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Stmt.Query(values) //nolint:staticcheck // fallback of drivers without QueryContext
+}
+
+// CheckNamedValue keeps the argument conversions of the driver, the statement's first.
+func (s *instrumentedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+
+	return s.conn.CheckNamedValue(nv)
+}
+
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("driver does not support named parameters")
+		}
+
+		values[i] = arg.Value
+	}
+
+	return values, nil
+}
@@ -0,0 +1,174 @@
+package dbkit
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasay-usmani/go-boilerplate/pkg/logkit"
+)
+
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []QueryEvent
+}
+
+func (r *eventRecorder) ObserveQuery(_ context.Context, e QueryEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+// newInstrumentedConn opens an instrumented SQLConn on a sqlmock database.
+func newInstrumentedConn(t *testing.T, l logkit.Logger, opts ...Option) (*SQLConn, sqlmock.Sqlmock) {
+	t.Helper()
+
+	dsn := "instrumented_" + t.Name()
+	mockDB, mock, err := sqlmock.NewWithDSN(dsn)
+	require.NoError(t, err)
+
+	conn, err := openSQLConn(context.Background(), "sqlmock", dsn, l, append(opts, WithMaxOpenConns(1)))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+		_ = mockDB.Close()
+	})
+
+	return conn, mock
+}
+
+func TestInstrumentedConn_ObservesQueries(t *testing.T) {
+	rec := &eventRecorder{}
+	conn, mock := newInstrumentedConn(t, testLogger, WithQueryObserver(rec))
+
+	errBoom := errors.New("boom")
+	mock.ExpectQuery("SELECT id FROM users").WithArgs("a@b.c").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("UPDATE users").WillReturnError(errBoom)
+	mock.ExpectBegin()
+	mock.ExpectPrepare("INSERT INTO `audit`").ExpectExec().WithArgs(7).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	var id int
+	require.NoError(t, conn.QueryRow("SELECT id FROM users WHERE email = ?", "a@b.c").Scan(&id))
+	_, err := conn.Exec("UPDATE users SET name = 'x' WHERE id = 1")
+	require.ErrorIs(t, err, errBoom)
+
+	err = conn.Atomic(context.Background(), nil, func(ctx context.Context, txn *sql.Tx) error {
+		stmt, err := txn.PrepareContext(ctx, "INSERT INTO `audit` (user_id) VALUES (?)")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		_, err = stmt.ExecContext(ctx, 7)
+		return err
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	require.Len(t, rec.events, 3)
+	assert.Equal(t, "select", rec.events[0].Operation)
+	assert.Equal(t, "users", rec.events[0].Table)
+	assert.Equal(t, 1, rec.events[0].Args)
+	assert.NoError(t, rec.events[0].Err)
+
+	assert.Equal(t, "update", rec.events[1].Operation)
+	assert.Equal(t, "UPDATE users SET name = '?' WHERE id = ?", rec.events[1].Query)
+	assert.ErrorIs(t, rec.events[1].Err, errBoom)
+
+	assert.Equal(t, "insert", rec.events[2].Operation)
+	assert.Equal(t, "audit", rec.events[2].Table)
+}
+
+func TestInstrumentedConn_LogsSlowQueries(t *testing.T) {
+	var out bytes.Buffer
+	l := logkit.NewLogger(logkit.Warn, "dbkit-test", logkit.WithOutput(&out))
+	t.Cleanup(func() { _ = logkit.SetLevel(logkit.Error) })
+
+	conn, mock := newInstrumentedConn(t, l, WithSlowQueryThreshold(10*time.Millisecond))
+	mock.ExpectExec("UPDATE accounts").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE accounts").WillDelayFor(20 * time.Millisecond).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err := conn.Exec("UPDATE accounts SET owner = 'fast'")
+	require.NoError(t, err)
+	assert.Empty(t, out.String())
+
+	_, err = conn.Exec("UPDATE accounts SET owner = 'secret' WHERE id = ?", 42)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "slow query")
+	assert.Contains(t, out.String(), "UPDATE accounts SET owner = '?' WHERE id = ?")
+	assert.NotContains(t, out.String(), "secret")
+}
+
+func TestRedactQuery(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{query: "SELECT * FROM t1 WHERE a = $1 AND b = ?", expected: "SELECT * FROM t1 WHERE a = $1 AND b = ?"},
+		{query: "SELECT * FROM users WHERE email = 'a@b.c' LIMIT 10", expected: "SELECT * FROM users WHERE email = '?' LIMIT ?"},
+		{query: "UPDATE t SET s = 'it''s', n = -1.5", expected: "UPDATE t SET s = '?', n = -?"},
+		{query: "SAVEPOINT sp_1", expected: "SAVEPOINT sp_1"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, RedactQuery(tt.query))
+	}
+}
+
+func TestClassifyQuery(t *testing.T) {
+	tests := []struct {
+		query     string
+		operation string
+		table     string
+	}{
+		{query: "SELECT `users`.* FROM `users` WHERE id = ?", operation: "select", table: "users"},
+		{query: "  insert into \"app\".\"orders\" (id) values ($1)", operation: "insert", table: "app.orders"},
+		{query: "DELETE FROM sessions", operation: "delete", table: "sessions"},
+		{query: "SAVEPOINT sp_1", operation: "savepoint", table: ""},
+		{query: "", operation: "", table: ""},
+	}
+
+	for _, tt := range tests {
+		operation, table := classifyQuery(tt.query)
+		assert.Equal(t, tt.operation, operation, tt.query)
+		assert.Equal(t, tt.table, table, tt.query)
+	}
+}
+
+func TestQueryHistogram(t *testing.T) {
+	h := NewQueryHistogram(0.01, 0.1)
+	ctx := context.Background()
+	h.ObserveQuery(ctx, QueryEvent{Operation: "select", Table: "users", Duration: 5 * time.Millisecond})
+	h.ObserveQuery(ctx, QueryEvent{Operation: "select", Table: "users", Duration: 50 * time.Millisecond})
+	h.ObserveQuery(ctx, QueryEvent{Operation: "select", Table: "users", Duration: time.Second})
+	h.ObserveQuery(ctx, QueryEvent{Operation: "insert", Table: "users", Duration: time.Millisecond, Err: errors.New("boom")})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	body := rec.Body.String()
+	for _, line := range []string{
+		`# TYPE db_query_duration_seconds histogram`,
+		`db_query_duration_seconds_bucket{operation="insert",status="error",table="users",le="0.01"} 1`,
+		`db_query_duration_seconds_count{operation="insert",status="error",table="users"} 1`,
+		`db_query_duration_seconds_bucket{operation="select",status="ok",table="users",le="0.01"} 1`,
+		`db_query_duration_seconds_bucket{operation="select",status="ok",table="users",le="0.1"} 2`,
+		`db_query_duration_seconds_bucket{operation="select",status="ok",table="users",le="+Inf"} 3`,
+		`db_query_duration_seconds_sum{operation="select",status="ok",table="users"} 1.055`,
+		`db_query_duration_seconds_count{operation="select",status="ok",table="users"} 3`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+}
@@ -0,0 +1,384 @@
+package dbkit
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
+	"github.com/wasay-usmani/go-boilerplate/pkg/errorx"
+)
+
+const (
+	OpEq     FilterOp = "eq"
+	OpIn     FilterOp = "in"
+	OpRange  FilterOp = "range"
+	OpPrefix FilterOp = "prefix"
+
+	_defaultMaxLimit  = 100
+	_valuesSeparator  = ","
+	_descendingPrefix = "-"
+)
+
+var _likeReplacer = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// FilterOp is a filter operator supported by ListSpec.
+type FilterOp string
+
+// ListSpec is the whitelist of an entity's list endpoint. Clients refer to fields
+// by their API name, only fields listed here can be sorted or filtered on and
+// only the mapped column names ever reach the query.
+//
+//	var usersList = dbkit.ListSpec{
+//		Sortable:   map[string]string{"name": "name", "created_at": "created_at"},
+//		Filterable: map[string]dbkit.FilterField{"status": {Column: "status", Ops: []dbkit.FilterOp{dbkit.OpEq, dbkit.OpIn}}},
+//		DefaultSort: []dbkit.Sort{{Field: "created_at", Direction: dbkit.DirectionDESC}},
+//		TieBreaker:  "id",
+//		MaxLimit:    50,
+//	}
+type ListSpec struct {
+	// Sortable maps sortable API field names to their column
+	Sortable map[string]string
+	// Filterable maps filterable API field names to their column and allowed operators
+	Filterable map[string]FilterField
+	// DefaultSort applies when the request has no sort
+	DefaultSort []Sort
+	// TieBreaker is a unique column appended to every sort, for a stable order across pages
+	TieBreaker string
+	// DefaultLimit defaults to 10, MaxLimit to 100, larger limits are capped
+	DefaultLimit int
+	MaxLimit     int
+}
+
+// FilterField is a filterable column and the operators allowed on it.
+type FilterField struct {
+	Column string
+	Ops    []FilterOp
+}
+
+// Sort is a sort on an API field, Direction is ASC or DESC and defaults to ASC.
+type Sort struct {
+	Field     string
+	Direction string
+}
+
+// Filter is a condition on an API field. Eq and prefix take one value, in one or
+// more and range two: the lower and upper bounds, both inclusive, nil when open.
+type Filter struct {
+	Field  string
+	Op     FilterOp
+	Values []any
+}
+
+// ListRequest is the client input of a list endpoint, as parsed from HTTP query
+// parameters with ParseSort and ParseFilters or built from a gRPC request.
+type ListRequest struct {
+	Sort    []Sort
+	Filters []Filter
+	Limit   int
+	Offset  uint64
+}
+
+// ListQuery is a validated ListRequest, holding column names from the ListSpec only.
+// Where conditions use ? placeholders, their arguments are in Args.
+type ListQuery struct {
+	Where   []string
+	Args    []any
+	OrderBy string
+	Limit   int
+	Offset  uint64
+}
+
+// ParseSort parses a comma separated sort parameter, e.g. "-created_at,name"
+// sorts by created_at descending, then by name ascending.
+func ParseSort(s string) []Sort {
+	var sorts []Sort
+	for _, field := range strings.Split(s, _valuesSeparator) {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		direction := DirectionASC
+		if after, ok := strings.CutPrefix(field, _descendingPrefix); ok {
+			field, direction = after, DirectionDESC
+		}
+
+		sorts = append(sorts, Sort{Field: field, Direction: direction})
+	}
+
+	return sorts
+}
+
+// ParseFilters parses the filter query parameters of the fields filterable in spec,
+// other parameters are ignored. The operator goes in brackets and defaults to eq,
+// in and range values are comma separated, an empty range bound is open:
+//
+//	?status=active&role[in]=admin,owner&created_at[range]=2024-01-01,&name[prefix]=jo
+func (s ListSpec) ParseFilters(values url.Values) []Filter {
+	var filters []Filter
+	for key, vals := range values {
+		field, op := key, OpEq
+		if name, rest, ok := strings.Cut(key, "["); ok && strings.HasSuffix(rest, "]") {
+			field, op = name, FilterOp(strings.TrimSuffix(rest, "]"))
+		}
+
+		if _, ok := s.Filterable[field]; !ok {
+			continue
+		}
+
+		for _, v := range vals {
+			filters = append(filters, Filter{Field: field, Op: op, Values: filterValues(op, v)})
+		}
+	}
+
+	// Map iteration order is random, keep the generated SQL stable
+	slices.SortStableFunc(filters, func(a, b Filter) int { return strings.Compare(a.Field+string(a.Op), b.Field+string(b.Op)) })
+	return filters
+}
+
+func filterValues(op FilterOp, v string) []any {
+	if op != OpIn && op != OpRange {
+		return []any{v}
+	}
+
+	parts := strings.Split(v, _valuesSeparator)
+	values := make([]any, len(parts))
+	for i, p := range parts {
+		if p != "" || op == OpIn {
+			values[i] = p
+		}
+	}
+
+	return values
+}
+
+// Build validates req against the spec. Unknown fields, disallowed operators and
+// malformed values return an errorx.BadRequest with the problems in Fields.
+func (s ListSpec) Build(req ListRequest) (*ListQuery, error) {
+	problems := map[string]any{}
+	q := &ListQuery{
+		OrderBy: s.orderBy(req.Sort, problems),
+		Limit:   s.limit(req.Limit),
+		Offset:  req.Offset,
+	}
+
+	for _, f := range req.Filters {
+		where, args, err := s.condition(f)
+		if err != nil {
+			problems[f.Field] = err.Error()
+			continue
+		}
+
+		q.Where = append(q.Where, where)
+		q.Args = append(q.Args, args...)
+	}
+
+	if len(problems) > 0 {
+		return nil, errorx.NewValidation("invalid list query", problems)
+	}
+
+	return q, nil
+}
+
+func (s ListSpec) orderBy(sorts []Sort, problems map[string]any) string {
+	if len(sorts) == 0 {
+		sorts = s.DefaultSort
+	}
+
+	var order []string
+	seen := map[string]bool{}
+	for _, srt := range sorts {
+		col, ok := s.Sortable[srt.Field]
+		if !ok {
+			problems["sort"] = fmt.Sprintf("field %q is not sortable", srt.Field)
+			continue
+		}
+
+		direction := strings.ToUpper(srt.Direction)
+		switch direction {
+		case "":
+			direction = DirectionASC
+		case DirectionASC, DirectionDESC:
+		default:
+			problems["sort"] = fmt.Sprintf("invalid direction %q", srt.Direction)
+			continue
+		}
+
+		if !seen[col] {
+			seen[col] = true
+			order = append(order, col+" "+direction)
+		}
+	}
+
+	if s.TieBreaker != "" && !seen[s.TieBreaker] {
+		order = append(order, s.TieBreaker+" "+DirectionASC)
+	}
+
+	return strings.Join(order, ", ")
+}
+
+func (s ListSpec) limit(limit int) int {
+	maxLimit := s.MaxLimit
+	if maxLimit <= 0 {
+		maxLimit = _defaultMaxLimit
+	}
+
+	if limit <= 0 {
+		limit = s.DefaultLimit
+	}
+
+	if limit <= 0 {
+		limit = _defaultLimit
+	}
+
+	return min(limit, maxLimit)
+}
+
+func (s ListSpec) condition(f Filter) (string, []any, error) {
+	field, ok := s.Filterable[f.Field]
+	if !ok {
+		return "", nil, errors.New("field is not filterable")
+	}
+
+	if !slices.Contains(field.Ops, f.Op) {
+		return "", nil, fmt.Errorf("operator %q is not allowed", f.Op)
+	}
+
+	col := field.Column
+	switch f.Op {
+	case OpEq:
+		if len(f.Values) != 1 {
+			return "", nil, errors.New("eq takes one value")
+		}
+
+		return col + " = ?", f.Values, nil
+	case OpIn:
+		if len(f.Values) == 0 {
+			return "", nil, errors.New("in takes at least one value")
+		}
+
+		return fmt.Sprintf("%s IN (%s)", col, placeholders(len(f.Values))), f.Values, nil
+	case OpRange:
+		return rangeCondition(col, f.Values)
+	case OpPrefix:
+		return prefixCondition(col, f.Values)
+	default:
+		return "", nil, fmt.Errorf("unknown operator %q", f.Op)
+	}
+}
+
+// prefixCondition escapes the LIKE wildcards of the prefix, backslash is the
+// default LIKE escape character of both MySQL and Postgres.
+func prefixCondition(col string, values []any) (string, []any, error) {
+	if len(values) != 1 {
+		return "", nil, errors.New("prefix takes one value")
+	}
+
+	prefix, ok := values[0].(string)
+	if !ok {
+		return "", nil, errors.New("prefix takes a string value")
+	}
+
+	return col + " LIKE ?", []any{_likeReplacer.Replace(prefix) + "%"}, nil
+}
+
+func rangeCondition(col string, values []any) (string, []any, error) {
+	if len(values) != 2 || (values[0] == nil && values[1] == nil) {
+		return "", nil, errors.New("range takes a lower and an upper bound")
+	}
+
+	var where []string
+	var args []any
+	if values[0] != nil {
+		where = append(where, col+" >= ?")
+		args = append(args, values[0])
+	}
+
+	if values[1] != nil {
+		where = append(where, col+" <= ?")
+		args = append(args, values[1])
+	}
+
+	return strings.Join(where, " AND "), args, nil
+}
+
+// QueryMods returns the where, order by, limit and offset query mods of the list.
+func (q *ListQuery) QueryMods() []qm.QueryMod {
+	mods := make([]qm.QueryMod, 0, len(q.Where)+3)
+	if where, args := q.WhereClause(); where != "" {
+		mods = append(mods, qm.Where(where, args...))
+	}
+
+	if q.OrderBy != "" {
+		mods = append(mods, qm.OrderBy(q.OrderBy))
+	}
+
+	mods = append(mods, qm.Limit(q.Limit))
+	if q.Offset > 0 {
+		mods = append(mods, qm.Offset(int(q.Offset)))
+	}
+
+	return mods
+}
+
+// WhereClause returns the conditions joined with AND, without the WHERE keyword, and their arguments.
+func (q *ListQuery) WhereClause() (string, []any) {
+	return strings.Join(q.Where, " AND "), q.Args
+}
+
+// SQL returns the list as parameterized SQL to append to a raw SELECT, i.e.
+// " WHERE ... ORDER BY ... LIMIT n OFFSET m", with the placeholders of dialect d.
+// Soft deleted rows are excluded unless WithDeleted is given. The condition is not
+// qualified, with joins pass WithDeleted and add the qualified one to Where.
+func (q *ListQuery) SQL(d Dialect, opts ...ScopeOption) (string, []any) {
+	var b strings.Builder
+	conditions := q.Where
+	if !newScopeOptions(opts).withDeleted {
+		conditions = append([]string{_notDeleted}, conditions...)
+	}
+
+	if len(conditions) > 0 {
+		b.WriteString(" WHERE " + strings.Join(conditions, " AND "))
+	}
+
+	if q.OrderBy != "" {
+		b.WriteString(" ORDER BY " + q.OrderBy)
+	}
+
+	b.WriteString(" LIMIT " + strconv.Itoa(q.Limit))
+	if q.Offset > 0 {
+		b.WriteString(" OFFSET " + strconv.FormatUint(q.Offset, 10))
+	}
+
+	query := b.String()
+	if d == Postgres {
+		query = numberPlaceholders(query)
+	}
+
+	return query, q.Args
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// numberPlaceholders rewrites ? placeholders to the $1, $2... form of Postgres.
+func numberPlaceholders(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+
+		n++
+		b.WriteString("$" + strconv.Itoa(n))
+	}
+
+	return b.String()
+}
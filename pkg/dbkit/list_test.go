@@ -0,0 +1,114 @@
+package dbkit
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasay-usmani/go-boilerplate/pkg/errorx"
+)
+
+var testListSpec = ListSpec{
+	Sortable: map[string]string{"name": "u.name", "created": "u.created_at"},
+	Filterable: map[string]FilterField{
+		"status":  {Column: "u.status", Ops: []FilterOp{OpEq, OpIn}},
+		"created": {Column: "u.created_at", Ops: []FilterOp{OpRange}},
+		"name":    {Column: "u.name", Ops: []FilterOp{OpPrefix}},
+	},
+	DefaultSort: []Sort{{Field: "created", Direction: DirectionDESC}},
+	TieBreaker:  "u.id",
+	MaxLimit:    50,
+}
+
+func TestParseSort(t *testing.T) {
+	assert.Equal(t, []Sort{{Field: "created", Direction: DirectionDESC}, {Field: "name", Direction: DirectionASC}},
+		ParseSort("-created, name,"))
+	assert.Empty(t, ParseSort(""))
+}
+
+func TestListSpec_ParseFilters(t *testing.T) {
+	values, err := url.ParseQuery("status[in]=a,b&created[range]=2024-01-01,&name[prefix]=jo&limit=5&other=x")
+	require.NoError(t, err)
+
+	assert.Equal(t, []Filter{
+		{Field: "created", Op: OpRange, Values: []any{"2024-01-01", nil}},
+		{Field: "name", Op: OpPrefix, Values: []any{"jo"}},
+		{Field: "status", Op: OpIn, Values: []any{"a", "b"}},
+	}, testListSpec.ParseFilters(values))
+}
+
+func TestListSpec_Build(t *testing.T) {
+	q, err := testListSpec.Build(ListRequest{
+		Sort: []Sort{{Field: "name", Direction: "desc"}, {Field: "created"}},
+		Filters: []Filter{
+			{Field: "status", Op: OpIn, Values: []any{"active", "invited"}},
+			{Field: "created", Op: OpRange, Values: []any{nil, "2024-12-31"}},
+			{Field: "name", Op: OpPrefix, Values: []any{"50%_off"}},
+		},
+		Limit:  500,
+		Offset: 20,
+	})
+	require.NoError(t, err)
+
+	query, args := q.SQL(MySQL)
+	assert.Equal(t, " WHERE deleted_at IS NULL AND u.status IN (?, ?) AND u.created_at <= ? AND u.name LIKE ?"+
+		" ORDER BY u.name DESC, u.created_at ASC, u.id ASC LIMIT 50 OFFSET 20", query)
+	assert.Equal(t, []any{"active", "invited", "2024-12-31", `50\%\_off%`}, args)
+
+	query, _ = q.SQL(Postgres)
+	assert.Contains(t, query, "u.status IN ($1, $2) AND u.created_at <= $3 AND u.name LIKE $4")
+
+	sql, modArgs := buildQuery(t, q.QueryMods())
+	assert.Equal(t, "SELECT * FROM `users` WHERE (u.status IN (?, ?) AND u.created_at <= ? AND u.name LIKE ?)"+
+		" ORDER BY u.name DESC, u.created_at ASC, u.id ASC LIMIT 50 OFFSET 20;", sql)
+	assert.Equal(t, args, modArgs)
+}
+
+func TestListSpec_BuildDefaults(t *testing.T) {
+	q, err := testListSpec.Build(ListRequest{})
+	require.NoError(t, err)
+
+	query, args := q.SQL(MySQL)
+	assert.Equal(t, " WHERE deleted_at IS NULL ORDER BY u.created_at DESC, u.id ASC LIMIT 10", query)
+	assert.Empty(t, args)
+
+	query, _ = q.SQL(MySQL, WithDeleted())
+	assert.Equal(t, " ORDER BY u.created_at DESC, u.id ASC LIMIT 10", query)
+}
+
+func TestListSpec_BuildRejectsInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		req   ListRequest
+		field string
+	}{
+		{name: "unknown sort field", req: ListRequest{Sort: []Sort{{Field: "name; DROP TABLE users"}}}, field: "sort"},
+		{name: "invalid direction", req: ListRequest{Sort: []Sort{{Field: "name", Direction: "sideways"}}}, field: "sort"},
+		{name: "unknown filter field", req: ListRequest{Filters: []Filter{{Field: "password", Op: OpEq, Values: []any{"x"}}}}, field: "password"},
+		{name: "operator not allowed", req: ListRequest{Filters: []Filter{{Field: "status", Op: OpPrefix, Values: []any{"a"}}}}, field: "status"},
+		{name: "empty in", req: ListRequest{Filters: []Filter{{Field: "status", Op: OpIn}}}, field: "status"},
+		{name: "open range", req: ListRequest{Filters: []Filter{{Field: "created", Op: OpRange, Values: []any{nil, nil}}}}, field: "created"},
+		{name: "non string prefix", req: ListRequest{Filters: []Filter{{Field: "name", Op: OpPrefix, Values: []any{1}}}}, field: "name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := testListSpec.Build(tt.req)
+			require.Error(t, err)
+			assert.True(t, errorx.Is(err, errorx.BadRequest))
+
+			var xerr *errorx.Error
+			require.ErrorAs(t, err, &xerr)
+			assert.Contains(t, xerr.Fields, tt.field)
+		})
+	}
+}
+
+func TestPrimaryOrDefaultSortBy(t *testing.T) {
+	assert.Equal(t, "name", PrimaryOrDefaultSortBy("name"))
+	assert.Equal(t, "u.name", PrimaryOrDefaultSortBy("u.name"))
+	assert.Equal(t, _defaultSortBy, PrimaryOrDefaultSortBy(""))
+	assert.Equal(t, _defaultSortBy, PrimaryOrDefaultSortBy("name; DROP TABLE users"))
+	assert.Equal(t, _defaultSortBy, PrimaryOrDefaultSortBy("(SELECT 1)"))
+}
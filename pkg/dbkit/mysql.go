@@ -1,25 +1,19 @@
 package dbkit
 
 import (
-	"database/sql"
-	"fmt"
+	"context"
 
 	"github.com/aarondl/sqlboiler/v4/boil"
 	_ "github.com/aarondl/sqlboiler/v4/drivers/sqlboiler-mysql/driver"
 	"github.com/wasay-usmani/go-boilerplate/pkg/logkit"
 )
 
-func LoadMySQLConn(mysqlURL string, debug bool, l logkit.Logger) (*SQLConn, error) {
-	sqldb, err := sql.Open("mysql", mysqlURL)
+func LoadMySQLConn(ctx context.Context, mysqlURL string, debug bool, l logkit.Logger, opts ...Option) (*SQLConn, error) {
+	conn, err := openSQLConn(ctx, "mysql", mysqlURL, l, opts)
 	if err != nil {
-		return nil, fmt.Errorf("cannot connect mysql: %w", err)
+		return nil, err
 	}
 
 	boil.DebugMode = debug
-	err = sqldb.Ping()
-	if err != nil {
-		return nil, fmt.Errorf("cannot ping mysql: %w", err)
-	}
-
-	return &SQLConn{sqldb, l}, nil
+	return conn, nil
 }
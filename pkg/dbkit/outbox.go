@@ -0,0 +1,363 @@
+package dbkit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wasay-usmani/go-boilerplate/pkg/logkit"
+	"github.com/wasay-usmani/go-boilerplate/pkg/timex"
+)
+
+const (
+	_defaultOutboxTable           = "outbox"
+	_defaultOutboxBatchSize       = 100
+	_defaultOutboxPollInterval    = time.Second
+	_defaultOutboxRetention       = 24 * time.Hour
+	_defaultOutboxCleanupInterval = time.Minute
+	_defaultOutboxClaimTimeout    = time.Minute
+	_defaultOutboxAttempts        = 10
+	_defaultOutboxBaseBackoff     = time.Second
+	_defaultOutboxMaxBackoff      = 5 * time.Minute
+	_maxOutboxErrorLength         = 1024
+)
+
+var (
+	// ErrNoTransaction is returned by Outbox.Enqueue called outside a transaction.
+	ErrNoTransaction = errors.New("outbox messages must be enqueued in a transaction")
+	// ErrNoPublisher is returned by the relay of an Outbox created without publisher.
+	ErrNoPublisher = errors.New("outbox has no publisher")
+)
+
+// OutboxMessage is an event waiting in the outbox table to be published.
+type OutboxMessage struct {
+	ID        int64
+	Topic     string
+	Payload   []byte
+	Attempts  int
+	CreatedAt time.Time
+}
+
+// Publisher delivers outbox messages to a broker. A batch is retried as a whole
+// when Publish fails, so delivery is at least once and consumers should dedupe
+// messages by ID.
+type Publisher interface {
+	Publish(ctx context.Context, msgs []OutboxMessage) error
+}
+
+// PublisherFunc adapts a function to a Publisher.
+type PublisherFunc func(ctx context.Context, msgs []OutboxMessage) error
+
+// Outbox implements the transactional outbox pattern: events are enqueued in the
+// transaction of the write they describe, so they exist if and only if it commits,
+// and a relay publishes them afterwards. The outbox table is created by the app
+// migrations, with the columns
+//
+//	id, topic, payload, attempts, last_error, available_at, created_at, delivered_at
+type Outbox struct {
+	db        *SQLConn
+	publisher Publisher
+	logger    logkit.Logger
+
+	dialect         Dialect
+	table           string
+	batchSize       int
+	pollInterval    time.Duration
+	retention       time.Duration
+	cleanupInterval time.Duration
+	claimTimeout    time.Duration
+	retry           RetryPolicy
+}
+
+// OutboxOption configures an Outbox.
+type OutboxOption func(*Outbox)
+
+func (f PublisherFunc) Publish(ctx context.Context, msgs []OutboxMessage) error {
+	return f(ctx, msgs)
+}
+
+// WithOutboxDialect sets the SQL dialect of the outbox queries, MySQL by default.
+func WithOutboxDialect(d Dialect) OutboxOption {
+	return func(o *Outbox) {
+		o.dialect = d
+	}
+}
+
+// WithOutboxTable sets the outbox table name, "outbox" by default.
+func WithOutboxTable(table string) OutboxOption {
+	return func(o *Outbox) {
+		o.table = table
+	}
+}
+
+// WithOutboxBatchSize sets the maximum number of messages handed to the publisher at once.
+func WithOutboxBatchSize(n int) OutboxOption {
+	return func(o *Outbox) {
+		o.batchSize = n
+	}
+}
+
+// WithOutboxPollInterval sets how often the relay looks for new messages when the outbox is drained.
+func WithOutboxPollInterval(d time.Duration) OutboxOption {
+	return func(o *Outbox) {
+		o.pollInterval = d
+	}
+}
+
+// WithOutboxRetention sets how long delivered messages are kept before the relay deletes them.
+func WithOutboxRetention(d time.Duration) OutboxOption {
+	return func(o *Outbox) {
+		o.retention = d
+	}
+}
+
+// WithOutboxClaimTimeout sets how long a relay has to publish the messages it claimed,
+// after which they are published again. It must exceed the publication time of a batch.
+func WithOutboxClaimTimeout(d time.Duration) OutboxOption {
+	return func(o *Outbox) {
+		o.claimTimeout = d
+	}
+}
+
+// WithOutboxRetry sets the attempts and backoff of failed publications. Messages
+// out of attempts stay undelivered in the table for inspection.
+func WithOutboxRetry(policy RetryPolicy) OutboxOption {
+	return func(o *Outbox) {
+		o.retry = policy
+	}
+}
+
+// NewOutbox returns an outbox on db, the primary database. publisher may be nil
+// when the outbox is only used to enqueue messages.
+func NewOutbox(db *SQLConn, publisher Publisher, l logkit.Logger, opts ...OutboxOption) *Outbox {
+	o := &Outbox{
+		db:              db,
+		publisher:       publisher,
+		logger:          l,
+		dialect:         MySQL,
+		table:           _defaultOutboxTable,
+		batchSize:       _defaultOutboxBatchSize,
+		pollInterval:    _defaultOutboxPollInterval,
+		retention:       _defaultOutboxRetention,
+		cleanupInterval: _defaultOutboxCleanupInterval,
+		claimTimeout:    _defaultOutboxClaimTimeout,
+		retry: RetryPolicy{
+			MaxAttempts: _defaultOutboxAttempts,
+			BaseBackoff: _defaultOutboxBaseBackoff,
+			MaxBackoff:  _defaultOutboxMaxBackoff,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	o.retry = o.retry.withDefaults()
+	return o
+}
+
+// HasPublisher reports whether the outbox has a publisher, i.e. whether Run relays messages.
+func (o *Outbox) HasPublisher() bool {
+	return o.publisher != nil
+}
+
+// Enqueue adds a message to the outbox in txn. When txn is nil the transaction
+// carried by ctx is used, see Atomic. It fails with ErrNoTransaction outside a transaction.
+func (o *Outbox) Enqueue(ctx context.Context, txn *sql.Tx, topic string, payload []byte) error {
+	if txn == nil {
+		var ok bool
+		if txn, ok = TxFromContext(ctx); !ok {
+			return ErrNoTransaction
+		}
+	}
+
+	now := timex.Now()
+	query := fmt.Sprintf("INSERT INTO %s (topic, payload, attempts, available_at, created_at) VALUES (?, ?, 0, ?, ?)", o.table)
+	if _, err := txn.ExecContext(ctx, o.rebind(query), topic, payload, now, now); err != nil {
+		return fmt.Errorf("failed to enqueue outbox message: %w", err)
+	}
+
+	return nil
+}
+
+// Run relays messages to the publisher until ctx is done, draining the outbox then
+// polling it every poll interval, and deletes delivered messages past their retention.
+// It is meant to be registered as a runkit.Manager worker. Several relays may run
+// concurrently, each message is claimed by a single one. Without publisher Run logs
+// a warning and returns, messages then wait in the outbox.
+func (o *Outbox) Run(ctx context.Context) error {
+	if o.publisher == nil {
+		o.logger.Warn("outbox has no publisher, messages are not relayed")
+		return nil
+	}
+
+	var lastCleanup time.Time
+	for {
+		n, err := o.Relay(ctx)
+		if err != nil && ctx.Err() == nil {
+			o.logger.Error("failed to relay outbox messages", err)
+		}
+
+		if time.Since(lastCleanup) >= o.cleanupInterval {
+			if _, err := o.Cleanup(ctx); err != nil && ctx.Err() == nil {
+				o.logger.Error("failed to clean up outbox messages", err)
+			}
+
+			lastCleanup = time.Now()
+		}
+
+		// A full batch means more messages are likely waiting
+		wait := o.pollInterval
+		if err == nil && n == o.batchSize {
+			wait = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Relay publishes one batch of due messages and returns its size. The messages are
+// claimed in a short transaction, locking them with FOR UPDATE SKIP LOCKED and
+// postponing them by the claim timeout, and published once it committed, so no
+// transaction is held open while publishing. They are then marked delivered, or
+// scheduled for a retry when the publisher fails. Messages of a relay stopped in
+// between are published again once their claim expires.
+func (o *Outbox) Relay(ctx context.Context) (int, error) {
+	if o.publisher == nil {
+		return 0, ErrNoPublisher
+	}
+
+	msgs, err := o.claim(ctx)
+	if err != nil || len(msgs) == 0 {
+		return 0, err
+	}
+
+	pubCtx, cancel := context.WithTimeout(ctx, o.claimTimeout)
+	defer cancel()
+
+	if pubErr := o.publisher.Publish(pubCtx, msgs); pubErr != nil {
+		o.logger.Warn("failed to publish outbox messages, retrying later", "error", pubErr, "messages", len(msgs))
+		return len(msgs), o.markFailed(ctx, msgs, pubErr)
+	}
+
+	return len(msgs), o.markDelivered(ctx, msgs)
+}
+
+// Cleanup deletes the messages delivered before the retention period and returns their number.
+func (o *Outbox) Cleanup(ctx context.Context) (int64, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE delivered_at IS NOT NULL AND delivered_at < ?", o.table)
+	res, err := o.db.ExecContext(ctx, o.rebind(query), timex.Now().Add(-o.retention))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete delivered outbox messages: %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+// claim locks a batch of due messages, counts the attempt and makes them unavailable
+// to other relays until the claim timeout.
+func (o *Outbox) claim(ctx context.Context) ([]OutboxMessage, error) {
+	var msgs []OutboxMessage
+	err := o.db.Atomic(ctx, nil, func(ctx context.Context, txn *sql.Tx) error {
+		var err error
+		if msgs, err = o.lockBatch(ctx, txn); err != nil || len(msgs) == 0 {
+			return err
+		}
+
+		ids := messageIDs(msgs)
+		query := fmt.Sprintf("UPDATE %s SET attempts = attempts + 1, available_at = ? WHERE id IN (%s)", o.table, placeholders(len(ids)))
+		if _, err := txn.ExecContext(ctx, o.rebind(query), append([]any{timex.Now().Add(o.claimTimeout)}, ids...)...); err != nil {
+			return fmt.Errorf("failed to claim outbox messages: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return msgs, nil
+}
+
+func (o *Outbox) lockBatch(ctx context.Context, txn *sql.Tx) ([]OutboxMessage, error) {
+	query := fmt.Sprintf(`SELECT id, topic, payload, attempts, created_at FROM %s
+		WHERE delivered_at IS NULL AND attempts < ? AND available_at <= ?
+		ORDER BY id LIMIT %d FOR UPDATE SKIP LOCKED`, o.table, o.batchSize)
+
+	rows, err := txn.QueryContext(ctx, o.rebind(query), o.retry.MaxAttempts, timex.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to select outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	var msgs []OutboxMessage
+	for rows.Next() {
+		var m OutboxMessage
+		if err := rows.Scan(&m.ID, &m.Topic, &m.Payload, &m.Attempts, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+
+		msgs = append(msgs, m)
+	}
+
+	return msgs, rows.Err()
+}
+
+func (o *Outbox) markDelivered(ctx context.Context, msgs []OutboxMessage) error {
+	ids := messageIDs(msgs)
+	query := fmt.Sprintf("UPDATE %s SET delivered_at = ? WHERE id IN (%s)", o.table, placeholders(len(ids)))
+	if _, err := o.db.ExecContext(ctx, o.rebind(query), append([]any{timex.Now()}, ids...)...); err != nil {
+		return fmt.Errorf("failed to mark outbox messages delivered: %w", err)
+	}
+
+	return nil
+}
+
+// markFailed schedules the next attempt of every message after its own backoff,
+// the attempt was counted by claim.
+func (o *Outbox) markFailed(ctx context.Context, msgs []OutboxMessage, pubErr error) error {
+	lastError := pubErr.Error()
+	if len(lastError) > _maxOutboxErrorLength {
+		lastError = lastError[:_maxOutboxErrorLength]
+	}
+
+	query := o.rebind(fmt.Sprintf("UPDATE %s SET last_error = ?, available_at = ? WHERE id = ?", o.table))
+	now := timex.Now()
+	for _, m := range msgs {
+		attempts := m.Attempts + 1
+		if attempts >= o.retry.MaxAttempts {
+			o.logger.Error("outbox message is out of attempts, giving up", pubErr, "id", m.ID, "topic", m.Topic)
+		}
+
+		availableAt := now.Add(o.retry.backoff(attempts))
+		if _, err := o.db.ExecContext(ctx, query, lastError, availableAt, m.ID); err != nil {
+			return fmt.Errorf("failed to reschedule outbox message %d: %w", m.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// rebind converts the ? placeholders of query to the dialect of the outbox.
+func (o *Outbox) rebind(query string) string {
+	if o.dialect == Postgres {
+		return numberPlaceholders(query)
+	}
+
+	return query
+}
+
+func messageIDs(msgs []OutboxMessage) []any {
+	ids := make([]any, len(msgs))
+	for i, m := range msgs {
+		ids[i] = m.ID
+	}
+
+	return ids
+}
@@ -0,0 +1,117 @@
+package dbkit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutbox_Enqueue(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	defer db.Close()
+
+	outbox := NewOutbox(db, nil, testLogger)
+	err := outbox.Enqueue(context.Background(), nil, "users.created", []byte(`{"id":1}`))
+	require.ErrorIs(t, err, ErrNoTransaction)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO outbox \(topic, payload, attempts, available_at, created_at\) VALUES \(\?, \?, 0, \?, \?\)`).
+		WithArgs("users.created", []byte(`{"id":1}`), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = db.Atomic(context.Background(), nil, func(ctx context.Context, _ *sql.Tx) error {
+		return outbox.Enqueue(ctx, nil, "users.created", []byte(`{"id":1}`))
+	})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutbox_RelayPublishesAndMarksDelivered(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	defer db.Close()
+
+	var published []OutboxMessage
+	var inTx bool
+	publisher := PublisherFunc(func(ctx context.Context, msgs []OutboxMessage) error {
+		_, inTx = TxFromContext(ctx)
+		published = append(published, msgs...)
+		return nil
+	})
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, topic, payload, attempts, created_at FROM outbox .* LIMIT 2 FOR UPDATE SKIP LOCKED`).
+		WithArgs(_defaultOutboxAttempts, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "topic", "payload", "attempts", "created_at"}).
+			AddRow(1, "users.created", []byte("a"), 0, now).
+			AddRow(2, "users.deleted", []byte("b"), 3, now))
+	mock.ExpectExec(`UPDATE outbox SET attempts = attempts \+ 1, available_at = \? WHERE id IN \(\?, \?\)`).
+		WithArgs(sqlmock.AnyArg(), int64(1), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	// Published once the claim committed, outside of any transaction
+	mock.ExpectExec(`UPDATE outbox SET delivered_at = \? WHERE id IN \(\?, \?\)`).
+		WithArgs(sqlmock.AnyArg(), int64(1), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	outbox := NewOutbox(db, publisher, testLogger, WithOutboxBatchSize(2))
+	n, err := outbox.Relay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.False(t, inTx)
+	require.Len(t, published, 2)
+	assert.Equal(t, OutboxMessage{ID: 2, Topic: "users.deleted", Payload: []byte("b"), Attempts: 3, CreatedAt: now}, published[1])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutbox_RelayReschedulesFailedMessages(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	defer db.Close()
+
+	errBroker := errors.New("broker unavailable")
+	publisher := PublisherFunc(func(context.Context, []OutboxMessage) error { return errBroker })
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, topic, payload, attempts, created_at FROM "events"`).
+		WithArgs(5, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "topic", "payload", "attempts", "created_at"}).
+			AddRow(7, "users.created", []byte("a"), 1, time.Now()))
+	mock.ExpectExec(`UPDATE "events" SET attempts = attempts \+ 1, available_at = \$1 WHERE id IN \(\$2\)`).
+		WithArgs(sqlmock.AnyArg(), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`UPDATE "events" SET last_error = \$1, available_at = \$2 WHERE id = \$3`).
+		WithArgs(errBroker.Error(), sqlmock.AnyArg(), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	outbox := NewOutbox(db, publisher, testLogger,
+		WithOutboxDialect(Postgres),
+		WithOutboxTable(`"events"`),
+		WithOutboxRetry(RetryPolicy{MaxAttempts: 5, BaseBackoff: time.Millisecond}),
+	)
+	n, err := outbox.Relay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutbox_Cleanup(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	defer db.Close()
+
+	mock.ExpectExec(`DELETE FROM outbox WHERE delivered_at IS NOT NULL AND delivered_at < \?`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	deleted, err := NewOutbox(db, nil, testLogger).Cleanup(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
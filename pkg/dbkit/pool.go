@@ -0,0 +1,176 @@
+package dbkit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/wasay-usmani/go-boilerplate/pkg/logkit"
+)
+
+const (
+	_defaultConnectTimeout = 5 * time.Second
+	_defaultConnectBackoff = time.Second
+	_maxConnectBackoff     = 30 * time.Second
+)
+
+// Option configures the connection pool of a SQLConn and how it connects.
+type Option func(*connOptions)
+
+type connOptions struct {
+	maxOpenConns    int
+	maxIdleConns    int
+	connMaxLifetime time.Duration
+	connMaxIdleTime time.Duration
+
+	connectTimeout time.Duration
+	connectRetries int
+	connectBackoff time.Duration
+
+	slowQueryThreshold time.Duration
+	observers          []QueryObserver
+}
+
+// WithMaxOpenConns limits the number of open connections, 0 means unlimited.
+func WithMaxOpenConns(n int) Option {
+	return func(o *connOptions) {
+		o.maxOpenConns = n
+	}
+}
+
+// WithMaxIdleConns sets the number of idle connections kept in the pool.
+func WithMaxIdleConns(n int) Option {
+	return func(o *connOptions) {
+		o.maxIdleConns = n
+	}
+}
+
+// WithConnMaxLifetime closes connections once they are older than d.
+func WithConnMaxLifetime(d time.Duration) Option {
+	return func(o *connOptions) {
+		o.connMaxLifetime = d
+	}
+}
+
+// WithConnMaxIdleTime closes connections idle for longer than d.
+func WithConnMaxIdleTime(d time.Duration) Option {
+	return func(o *connOptions) {
+		o.connMaxIdleTime = d
+	}
+}
+
+// WithConnectTimeout bounds each connection attempt, defaults to 5 seconds.
+func WithConnectTimeout(d time.Duration) Option {
+	return func(o *connOptions) {
+		o.connectTimeout = d
+	}
+}
+
+// WithConnectRetry retries a failed connection up to retries times, waiting
+// backoff before the first retry and doubling it on every following one.
+// Useful when the database starts alongside the service, e.g. in docker-compose.
+func WithConnectRetry(retries int, backoff time.Duration) Option {
+	return func(o *connOptions) {
+		o.connectRetries = retries
+		o.connectBackoff = backoff
+	}
+}
+
+// WithConnectRetries sets the retries of WithConnectRetry, keeping its backoff.
+func WithConnectRetries(retries int) Option {
+	return func(o *connOptions) {
+		o.connectRetries = retries
+	}
+}
+
+// WithConnectBackoff sets the backoff of WithConnectRetry, defaults to a second.
+func WithConnectBackoff(backoff time.Duration) Option {
+	return func(o *connOptions) {
+		o.connectBackoff = backoff
+	}
+}
+
+func newConnOptions(opts []Option) *connOptions {
+	o := &connOptions{
+		maxIdleConns:   -1,
+		connectTimeout: _defaultConnectTimeout,
+		connectBackoff: _defaultConnectBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// applyPool configures the pool, leaving the database/sql defaults for unset values.
+func (o *connOptions) applyPool(db *sql.DB) {
+	db.SetMaxOpenConns(o.maxOpenConns)
+	if o.maxIdleConns >= 0 {
+		db.SetMaxIdleConns(o.maxIdleConns)
+	}
+
+	db.SetConnMaxLifetime(o.connMaxLifetime)
+	db.SetConnMaxIdleTime(o.connMaxIdleTime)
+}
+
+// connect pings the database until it answers, the retries are exhausted or ctx
+// is done, e.g. on a shutdown signal while waiting for the database.
+func (o *connOptions) connect(ctx context.Context, db *sql.DB, driver string, l logkit.Logger) error {
+	backoff := o.connectBackoff
+	for attempt := 0; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, o.connectTimeout)
+		err := db.PingContext(pingCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= o.connectRetries || ctx.Err() != nil {
+			return fmt.Errorf("cannot ping %s after %d attempts: %w", driver, attempt+1, err)
+		}
+
+		if l != nil {
+			l.Warn(fmt.Sprintf("cannot ping %s, retrying", driver), "error", err, "attempt", attempt+1, "backoff", backoff.String())
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("cannot ping %s after %d attempts: %w", driver, attempt+1, ctx.Err())
+		case <-time.After(backoff):
+		}
+
+		backoff = min(2*backoff, _maxConnectBackoff)
+	}
+}
+
+// instrumented reports whether queries must be timed, see WithSlowQueryThreshold and WithQueryObserver.
+func (o *connOptions) instrumented() bool {
+	return o.slowQueryThreshold > 0 || len(o.observers) > 0
+}
+
+func openSQLConn(ctx context.Context, driver, dbURL string, l logkit.Logger, opts []Option) (*SQLConn, error) {
+	o := newConnOptions(opts)
+
+	var sqldb *sql.DB
+	var err error
+	if o.instrumented() {
+		sqldb, err = openInstrumentedDB(driver, dbURL, &instrumenter{logger: l, slowQueryThreshold: o.slowQueryThreshold, observers: o.observers})
+	} else {
+		sqldb, err = sql.Open(driver, dbURL)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect %s: %w", driver, err)
+	}
+
+	o.applyPool(sqldb)
+	if err := o.connect(ctx, sqldb, driver, l); err != nil {
+		_ = sqldb.Close()
+		return nil, err
+	}
+
+	return &SQLConn{sqldb, l}, nil
+}
@@ -0,0 +1,98 @@
+package dbkit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasay-usmani/go-boilerplate/pkg/logkit"
+)
+
+var testLogger = logkit.NewLogger(logkit.Error, "dbkit-test")
+
+func TestConnect_RetriesUntilReady(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	mock.ExpectPing()
+
+	o := newConnOptions([]Option{WithConnectRetry(2, time.Millisecond)})
+	require.NoError(t, o.connect(context.Background(), db, "mysql", testLogger))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConnect_GivesUpAfterRetries(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+
+	o := newConnOptions([]Option{WithConnectRetry(1, time.Millisecond)})
+	err = o.connect(context.Background(), db, "mysql", testLogger)
+	assert.ErrorContains(t, err, "cannot ping mysql after 2 attempts")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConnect_StopsWhenContextDone(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	o := newConnOptions([]Option{WithConnectRetry(5, time.Hour)})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	err = o.connect(ctx, db, "mysql", testLogger)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConnOptions(t *testing.T) {
+	o := newConnOptions([]Option{
+		WithConnectTimeout(time.Second),
+		WithConnMaxIdleTime(time.Minute),
+		WithMaxOpenConns(10),
+		WithConnMaxLifetime(time.Minute),
+		WithConnMaxIdleTime(0),
+		WithConnectRetries(3),
+	})
+
+	assert.Equal(t, 10, o.maxOpenConns)
+	assert.Equal(t, -1, o.maxIdleConns)
+	assert.Equal(t, time.Minute, o.connMaxLifetime)
+	// Set to zero explicitly, overriding the earlier option
+	assert.Zero(t, o.connMaxIdleTime)
+	assert.Equal(t, time.Second, o.connectTimeout)
+	assert.Equal(t, 3, o.connectRetries)
+	assert.Equal(t, _defaultConnectBackoff, o.connectBackoff)
+
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	o.applyPool(db)
+	assert.Equal(t, 10, db.Stats().MaxOpenConnections)
+}
+
+func TestConnOptions_NoIdleConns(t *testing.T) {
+	o := newConnOptions([]Option{WithMaxIdleConns(0)})
+	assert.Equal(t, 0, o.maxIdleConns)
+
+	// Unset settings keep the database/sql default of 2 idle connections
+	o = newConnOptions(nil)
+	assert.Equal(t, -1, o.maxIdleConns)
+}
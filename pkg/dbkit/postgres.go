@@ -1,8 +1,7 @@
 package dbkit
 
 import (
-	"database/sql"
-	"fmt"
+	"context"
 
 	"github.com/aarondl/sqlboiler/v4/boil"
 	_ "github.com/lib/pq"
@@ -10,20 +9,15 @@ import (
 )
 
 // The LoadPostgresSqlConn function can be used for redshift as well as postgres
-func LoadPostgresSQLConn(postgresURL string, debug bool, l logkit.Logger) (*SQLConn, error) {
-	sqldb, err := sql.Open("postgres", postgresURL)
+func LoadPostgresSQLConn(ctx context.Context, postgresURL string, debug bool, l logkit.Logger, opts ...Option) (*SQLConn, error) {
+	conn, err := openSQLConn(ctx, "postgres", postgresURL, l, opts)
 	if err != nil {
-		return nil, fmt.Errorf("cannot connect postgres: %w", err)
-	}
-
-	err = sqldb.Ping()
-	if err != nil {
-		return nil, fmt.Errorf("cannot ping postgres: %w", err)
+		return nil, err
 	}
 
 	if debug {
 		boil.DebugMode = true
 	}
 
-	return &SQLConn{sqldb, l}, nil
+	return conn, nil
 }
@@ -0,0 +1,136 @@
+package dbkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wasay-usmani/go-boilerplate/pkg/logkit"
+	"github.com/wasay-usmani/go-boilerplate/pkg/timex"
+)
+
+const (
+	_defaultPurgeRetention = 30 * 24 * time.Hour
+	_defaultPurgeInterval  = time.Hour
+)
+
+// Purger hard deletes the soft deleted rows of tables once their deleted_at is older
+// than the retention period.
+type Purger struct {
+	db     *SQLConn
+	logger logkit.Logger
+	tables []string
+
+	dialect   Dialect
+	retention time.Duration
+	interval  time.Duration
+}
+
+// PurgerOption configures a Purger.
+type PurgerOption func(*Purger)
+
+// WithPurgeDialect sets the SQL dialect of the purge queries, MySQL by default.
+func WithPurgeDialect(d Dialect) PurgerOption {
+	return func(p *Purger) {
+		p.dialect = d
+	}
+}
+
+// WithPurgeRetention sets how long soft deleted rows are kept, 30 days by default.
+// A retention of 0 disables purging.
+func WithPurgeRetention(d time.Duration) PurgerOption {
+	return func(p *Purger) {
+		p.retention = d
+	}
+}
+
+// WithPurgeInterval sets how often Run purges the tables, hourly by default.
+func WithPurgeInterval(d time.Duration) PurgerOption {
+	return func(p *Purger) {
+		p.interval = d
+	}
+}
+
+// NewPurger returns a purger of tables on db, the primary database. Tables are purged
+// in order, so list child tables before the tables their foreign keys reference.
+func NewPurger(db *SQLConn, l logkit.Logger, tables []string, opts ...PurgerOption) *Purger {
+	p := &Purger{
+		db:        db,
+		logger:    l,
+		tables:    tables,
+		dialect:   MySQL,
+		retention: _defaultPurgeRetention,
+		interval:  _defaultPurgeInterval,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Enabled reports whether there is something to purge: tables and a retention.
+func (p *Purger) Enabled() bool {
+	return len(p.tables) > 0 && p.retention > 0
+}
+
+// Run purges the tables every interval until ctx is done. It is meant to be registered
+// as a runkit.Manager worker and returns right away when there is nothing to purge.
+func (p *Purger) Run(ctx context.Context) error {
+	if !p.Enabled() {
+		p.logger.Debug("soft deleted rows purge is disabled")
+		return nil
+	}
+
+	for {
+		if _, err := p.Purge(ctx); err != nil && ctx.Err() == nil {
+			p.logger.Error("failed to purge soft deleted rows", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(p.interval):
+		}
+	}
+}
+
+// Purge hard deletes the rows soft deleted before the retention period and returns
+// their number. A failing table does not stop the others from being purged.
+func (p *Purger) Purge(ctx context.Context) (int64, error) {
+	cutoff := timex.Now().Add(-p.retention)
+
+	var (
+		total int64
+		errs  []error
+	)
+
+	for _, table := range p.tables {
+		query := fmt.Sprintf("DELETE FROM %s WHERE %s IS NOT NULL AND %s < ?", table, DeletedAtColumn, DeletedAtColumn)
+		if p.dialect == Postgres {
+			query = numberPlaceholders(query)
+		}
+
+		res, err := p.db.ExecContext(ctx, query, cutoff)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to purge %s: %w", table, err))
+			continue
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if n > 0 {
+			p.logger.Info("purged soft deleted rows", "table", table, "rows", n)
+		}
+
+		total += n
+	}
+
+	return total, errors.Join(errs...)
+}
@@ -0,0 +1,50 @@
+package dbkit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurger_Purge(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	defer db.Close()
+
+	errLocked := errors.New("lock wait timeout")
+	mock.ExpectExec(`DELETE FROM comments WHERE deleted_at IS NOT NULL AND deleted_at < \$1`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnError(errLocked)
+	mock.ExpectExec(`DELETE FROM posts WHERE deleted_at IS NOT NULL AND deleted_at < \$1`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	purger := NewPurger(db, testLogger, []string{"comments", "posts"},
+		WithPurgeDialect(Postgres),
+		WithPurgeRetention(7*24*time.Hour),
+	)
+	n, err := purger.Purge(context.Background())
+	require.ErrorIs(t, err, errLocked)
+	assert.Equal(t, int64(4), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurger_RunDisabled(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	defer db.Close()
+
+	for _, p := range []*Purger{
+		NewPurger(db, testLogger, nil),
+		NewPurger(db, testLogger, []string{"posts"}, WithPurgeRetention(0)),
+	} {
+		assert.False(t, p.Enabled())
+		require.NoError(t, p.Run(context.Background()))
+	}
+
+	assert.True(t, NewPurger(db, testLogger, []string{"posts"}).Enabled())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,70 @@
+package dbkit
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	_queryDurationMetric = "db_query_duration_seconds"
+	_statusOK            = "ok"
+	_statusError         = "error"
+)
+
+// _defaultQueryBuckets are the histogram upper bounds, in seconds
+var _defaultQueryBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// QueryHistogram is a QueryObserver recording query durations by operation, table and
+// status (ok or error) in a Prometheus histogram. It serves its registry, e.g. on
+// httpx.MetricsPath.
+type QueryHistogram struct {
+	durations *prometheus.HistogramVec
+	registry  *prometheus.Registry
+	handler   http.Handler
+}
+
+// NewQueryHistogram returns a histogram with the given bucket upper bounds in seconds,
+// from 1ms to 5s when none are given, registered on a new registry.
+func NewQueryHistogram(buckets ...float64) *QueryHistogram {
+	if len(buckets) == 0 {
+		buckets = _defaultQueryBuckets
+	}
+
+	durations := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    _queryDurationMetric,
+		Help:    "Duration of SQL queries by operation and table.",
+		Buckets: buckets,
+	}, []string{"operation", "table", "status"})
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(durations)
+
+	return &QueryHistogram{
+		durations: durations,
+		registry:  registry,
+		handler:   promhttp.HandlerFor(registry, promhttp.HandlerOpts{}),
+	}
+}
+
+func (h *QueryHistogram) ObserveQuery(_ context.Context, e QueryEvent) {
+	status := _statusOK
+	if e.Err != nil {
+		status = _statusError
+	}
+
+	h.durations.WithLabelValues(e.Operation, e.Table, status).Observe(e.Duration.Seconds())
+}
+
+// Registry returns the registry served by the histogram, to expose other metrics
+// of the service on the same endpoint.
+func (h *QueryHistogram) Registry() *prometheus.Registry {
+	return h.registry
+}
+
+// ServeHTTP serves the registry to Prometheus scrapers.
+func (h *QueryHistogram) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.handler.ServeHTTP(w, r)
+}
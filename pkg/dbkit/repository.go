@@ -0,0 +1,394 @@
+package dbkit
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
+	"github.com/wasay-usmani/go-boilerplate/pkg/errorx"
+)
+
+var (
+	// ErrUpsertNotSupported is returned by Repository.Upsert for models without the MySQL or Postgres Upsert signature.
+	ErrUpsertNotSupported = errors.New("model does not support upsert, call its Upsert with Repository.Executor")
+	// ErrUpsertTenantConflict is returned by Repository.Upsert of tenant scoped Postgres models
+	// when the conflict columns do not include TenantColumn.
+	ErrUpsertTenantConflict = errors.New("tenant scoped upsert needs conflict columns including " + TenantColumn)
+	// ErrDeleteNotSupported is returned by Repository.Delete for models without a generated Delete.
+	ErrDeleteNotSupported = errors.New("model does not support delete")
+)
+
+// Executor returns the executor queries run on for ctx: the transaction it carries,
+// or a connection pool outside transactions. SQLConn and Router implement it.
+type Executor interface {
+	Executor(ctx context.Context) boil.ContextExecutor
+}
+
+// Model is the row API of sqlboiler generated models, e.g. *models.User. Models also
+// implement Deleter, or SoftDeleter when generated with --add-soft-deletes.
+type Model interface {
+	Insert(ctx context.Context, exec boil.ContextExecutor, columns boil.Columns) error
+	Update(ctx context.Context, exec boil.ContextExecutor, columns boil.Columns) (int64, error)
+}
+
+// Deleter is the Delete of sqlboiler models without soft deletes.
+type Deleter interface {
+	Delete(ctx context.Context, exec boil.ContextExecutor) (int64, error)
+}
+
+// SoftDeleter is the Delete of sqlboiler models generated with --add-soft-deletes,
+// which sets deleted_at unless hardDelete is true.
+type SoftDeleter interface {
+	Delete(ctx context.Context, exec boil.ContextExecutor, hardDelete bool) (int64, error)
+}
+
+// Query is the finisher API of sqlboiler generated queries, e.g. the query returned by models.Users.
+type Query[M Model, S ~[]M] interface {
+	One(ctx context.Context, exec boil.ContextExecutor) (M, error)
+	All(ctx context.Context, exec boil.ContextExecutor) (S, error)
+	Count(ctx context.Context, exec boil.ContextExecutor) (int64, error)
+	Exists(ctx context.Context, exec boil.ContextExecutor) (bool, error)
+}
+
+// MySQLUpserter is the Upsert of sqlboiler models generated for MySQL.
+type MySQLUpserter interface {
+	Upsert(ctx context.Context, exec boil.ContextExecutor, updateColumns, insertColumns boil.Columns) error
+}
+
+// PostgresUpserter is the Upsert of sqlboiler models generated for Postgres. Models
+// generated by recent sqlboiler versions also take the UpsertOptionFunc options of
+// their package, Repository.Upsert supports both.
+type PostgresUpserter interface {
+	Upsert(ctx context.Context, exec boil.ContextExecutor, updateOnConflict bool, conflictColumns []string,
+		updateColumns, insertColumns boil.Columns) error
+}
+
+// Repository provides the CRUD operations of a sqlboiler model. Every operation runs
+// on the executor of its ctx, so it joins the transaction of WrapAtomic/Atomic when
+// there is one. Errors are mapped with MapError, e.g. a missing row is an errorx.NotFound.
+// Queries of models generated with --add-soft-deletes exclude soft deleted rows, pass
+// qm.WithDeleted() to include them.
+//
+//	users := dbkit.NewRepository[*models.User, models.UserSlice](db, models.Users, models.UserColumns.ID)
+//	user, err := users.Get(ctx, id)
+type Repository[M Model, S ~[]M, Q Query[M, S]] struct {
+	db    Executor
+	query func(mods ...qm.QueryMod) Q
+	pk    string
+
+	tenantScoped    bool
+	conflictColumns []string
+}
+
+// RepositoryOption customizes a Repository.
+type RepositoryOption func(*repositoryOptions)
+
+type repositoryOptions struct {
+	tenantScoped    bool
+	conflictColumns []string
+}
+
+// WithTenantScope scopes every operation to the tenant of its ctx, see tenantkit:
+// queries are restricted to the rows of the tenant, Create sets the tenant_id of the
+// model and Update and Delete fail unless both the model and its row belong to the
+// tenant. Operations fail with an errorx.Forbidden when ctx carries no tenant. The
+// unique keys of the table must include tenant_id, so Upsert cannot update the row
+// of another tenant.
+func WithTenantScope() RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.tenantScoped = true
+	}
+}
+
+// WithConflictColumns sets the columns of the unique key Upsert of Postgres models
+// conflicts on, the primary key when none are given. MySQL models conflict on
+// every unique key of the table.
+func WithConflictColumns(columns ...string) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.conflictColumns = columns
+	}
+}
+
+// NewRepository returns the repository of the model queried by query, the generated
+// models.<Plural> function, whose primary key column is pk.
+func NewRepository[M Model, S ~[]M, Q Query[M, S]](
+	db Executor, query func(mods ...qm.QueryMod) Q, pk string, opts ...RepositoryOption,
+) *Repository[M, S, Q] {
+	var o repositoryOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Repository[M, S, Q]{
+		db:              db,
+		query:           query,
+		pk:              pk,
+		tenantScoped:    o.tenantScoped,
+		conflictColumns: o.conflictColumns,
+	}
+}
+
+// Executor returns the executor of ctx, for the queries the repository does not cover.
+func (r *Repository[M, S, Q]) Executor(ctx context.Context) boil.ContextExecutor {
+	return r.db.Executor(ctx)
+}
+
+// Get returns the row with the primary key id.
+func (r *Repository[M, S, Q]) Get(ctx context.Context, id any) (M, error) {
+	return r.Find(ctx, qm.Where(r.pk+" = ?", id))
+}
+
+// Find returns the first row matching mods.
+func (r *Repository[M, S, Q]) Find(ctx context.Context, mods ...qm.QueryMod) (M, error) {
+	var zero M
+	mods, err := r.scope(ctx, mods)
+	if err != nil {
+		return zero, err
+	}
+
+	m, err := r.query(mods...).One(ctx, r.Executor(ctx))
+	if err != nil {
+		return zero, MapError(err)
+	}
+
+	return m, nil
+}
+
+// List returns the rows matching mods, e.g. the QueryMods of a ListQuery or a Keyset.
+func (r *Repository[M, S, Q]) List(ctx context.Context, mods ...qm.QueryMod) (S, error) {
+	mods, err := r.scope(ctx, mods)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.query(mods...).All(ctx, r.Executor(ctx))
+	if err != nil {
+		return nil, MapError(err)
+	}
+
+	return rows, nil
+}
+
+// Count returns the number of rows matching mods.
+func (r *Repository[M, S, Q]) Count(ctx context.Context, mods ...qm.QueryMod) (int64, error) {
+	mods, err := r.scope(ctx, mods)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := r.query(mods...).Count(ctx, r.Executor(ctx))
+	return n, MapError(err)
+}
+
+// Exists reports whether a row matches mods.
+func (r *Repository[M, S, Q]) Exists(ctx context.Context, mods ...qm.QueryMod) (bool, error) {
+	mods, err := r.scope(ctx, mods)
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := r.query(mods...).Exists(ctx, r.Executor(ctx))
+	return ok, MapError(err)
+}
+
+// Create inserts m, setting the columns generated by the database back on it.
+func (r *Repository[M, S, Q]) Create(ctx context.Context, m M) error {
+	if r.tenantScoped {
+		if err := stampTenant(ctx, m); err != nil {
+			return err
+		}
+	}
+
+	return MapError(m.Insert(ctx, r.Executor(ctx), boil.Infer()))
+}
+
+// Update saves m, only the given columns when there are some, every column but the
+// primary key otherwise. The given columns are completed with the updated_at and
+// updated_by columns of m, which AuditUpdateHook stamps.
+func (r *Repository[M, S, Q]) Update(ctx context.Context, m M, columns ...string) error {
+	if err := r.checkTenant(ctx, m); err != nil {
+		return err
+	}
+
+	if len(columns) > 0 {
+		columns = append(slices.Clip(columns), auditColumns(m, columns)...)
+	}
+
+	_, err := m.Update(ctx, r.Executor(ctx), whitelistOrInfer(columns))
+	return MapError(err)
+}
+
+// Delete deletes m, setting its deleted_at when it is a SoftDeleter. It returns an
+// errorx.NotFound when m does not exist.
+func (r *Repository[M, S, Q]) Delete(ctx context.Context, m M) error {
+	return r.delete(ctx, m, false)
+}
+
+// HardDelete deletes the row of m, even when it is a SoftDeleter.
+func (r *Repository[M, S, Q]) HardDelete(ctx context.Context, m M) error {
+	return r.delete(ctx, m, true)
+}
+
+func (r *Repository[M, S, Q]) delete(ctx context.Context, m M, hardDelete bool) error {
+	if err := r.checkTenant(ctx, m); err != nil {
+		return err
+	}
+
+	var (
+		n   int64
+		err error
+	)
+
+	switch d := any(m).(type) {
+	case SoftDeleter:
+		n, err = d.Delete(ctx, r.Executor(ctx), hardDelete)
+	case Deleter:
+		n, err = d.Delete(ctx, r.Executor(ctx))
+	default:
+		return ErrDeleteNotSupported
+	}
+
+	if err != nil {
+		return MapError(err)
+	}
+
+	if n == 0 {
+		return errorx.New(errorx.NotFound, "record not found")
+	}
+
+	return nil
+}
+
+// Upsert inserts m or, when it conflicts with an existing row, updates updateColumns of
+// that row, every column when none are given. Postgres models conflict on the columns
+// set with WithConflictColumns, which must include TenantColumn when the repository
+// is tenant scoped.
+func (r *Repository[M, S, Q]) Upsert(ctx context.Context, m M, updateColumns ...string) error {
+	upsert, ok := r.upserter(m)
+	if !ok {
+		return ErrUpsertNotSupported
+	}
+
+	if r.tenantScoped {
+		if err := stampTenant(ctx, m); err != nil {
+			return err
+		}
+	}
+
+	return MapError(upsert(ctx, r.Executor(ctx), whitelistOrInfer(updateColumns), boil.Infer()))
+}
+
+type upsertFunc func(ctx context.Context, exec boil.ContextExecutor, updateColumns, insertColumns boil.Columns) error
+
+// upserter returns the Upsert of m, with the conflict columns of the repository bound
+// for Postgres models.
+func (r *Repository[M, S, Q]) upserter(m M) (upsertFunc, bool) {
+	if upserter, ok := any(m).(MySQLUpserter); ok {
+		return upserter.Upsert, true
+	}
+
+	method, ok := postgresUpsertMethod(m)
+	if !ok {
+		return nil, false
+	}
+
+	return func(ctx context.Context, exec boil.ContextExecutor, updateColumns, insertColumns boil.Columns) error {
+		if r.tenantScoped && !slices.Contains(r.conflictColumns, TenantColumn) {
+			return ErrUpsertTenantConflict
+		}
+
+		out := method.Call([]reflect.Value{
+			reflect.ValueOf(&ctx).Elem(),
+			reflect.ValueOf(&exec).Elem(),
+			reflect.ValueOf(true),
+			reflect.ValueOf(r.conflictColumns),
+			reflect.ValueOf(updateColumns),
+			reflect.ValueOf(insertColumns),
+		})
+
+		err, _ := out[0].Interface().(error)
+		return err
+	}, true
+}
+
+// postgresUpsertMethod returns the Upsert method of m when it has the PostgresUpserter
+// signature, optionally followed by variadic options left empty.
+func postgresUpsertMethod(m any) (reflect.Value, bool) {
+	method := reflect.ValueOf(m).MethodByName("Upsert")
+	if !method.IsValid() {
+		return reflect.Value{}, false
+	}
+
+	want := reflect.TypeFor[PostgresUpserter]().Method(0).Type
+	got := method.Type()
+	switch {
+	case got == want:
+		return method, true
+	case got.NumIn() != want.NumIn()+1 || !got.IsVariadic() || got.NumOut() != want.NumOut() || got.Out(0) != want.Out(0):
+		return reflect.Value{}, false
+	}
+
+	for i := range want.NumIn() {
+		if got.In(i) != want.In(i) {
+			return reflect.Value{}, false
+		}
+	}
+
+	return method, true
+}
+
+// scope prepends the tenant condition to mods when the repository is tenant scoped.
+// Soft deleted rows are left to the generated queries, which honor qm.WithDeleted.
+func (r *Repository[M, S, Q]) scope(ctx context.Context, mods []qm.QueryMod) ([]qm.QueryMod, error) {
+	if !r.tenantScoped {
+		return mods, nil
+	}
+
+	scope, err := TenantScope(ctx, WithDeleted())
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]qm.QueryMod{scope}, mods...), nil
+}
+
+// checkTenant ensures m and its stored row belong to the tenant of ctx. The row is
+// read again since the Update and Delete of models only filter on the primary key,
+// so a model carrying a forged tenant_id cannot write the row of another tenant.
+func (r *Repository[M, S, Q]) checkTenant(ctx context.Context, m M) error {
+	if !r.tenantScoped {
+		return nil
+	}
+
+	if err := checkTenant(ctx, m); err != nil {
+		return err
+	}
+
+	pk, ok := modelFields(m)[r.pk]
+	if !ok {
+		return errorx.New(errorx.Internal, "tenant scoped model has no "+r.pk+" column")
+	}
+
+	// Read on the primary, a replica may lag behind a row just created or moved
+	ok, err := r.Exists(WithPrimary(ctx), qm.Where(r.pk+" = ?", pk.Interface()), qm.WithDeleted())
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return errorx.New(errorx.NotFound, "record not found")
+	}
+
+	return nil
+}
+
+func whitelistOrInfer(columns []string) boil.Columns {
+	if len(columns) == 0 {
+		return boil.Infer()
+	}
+
+	return boil.Whitelist(columns...)
+}
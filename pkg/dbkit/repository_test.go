@@ -0,0 +1,263 @@
+package dbkit
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/aarondl/sqlboiler/v4/drivers"
+	"github.com/aarondl/sqlboiler/v4/queries"
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasay-usmani/go-boilerplate/pkg/errorx"
+	"github.com/wasay-usmani/go-boilerplate/pkg/tenantkit"
+)
+
+// thing and thingQuery mimic the model and query sqlboiler generates for a things table.
+type thing struct {
+	ID   int
+	Name string
+}
+
+type thingSlice []*thing
+
+type thingQuery struct {
+	*queries.Query
+}
+
+func things(mods ...qm.QueryMod) thingQuery {
+	q := &queries.Query{}
+	queries.SetDialect(q, &drivers.Dialect{LQ: '`', RQ: '`'})
+	queries.SetFrom(q, "`things`")
+	qm.Apply(q, mods...)
+	return thingQuery{q}
+}
+
+func (q thingQuery) One(ctx context.Context, exec boil.ContextExecutor) (*thing, error) {
+	queries.SetLimit(q.Query, 1)
+	query, args := queries.BuildQuery(q.Query)
+	t := &thing{}
+	if err := exec.QueryRowContext(ctx, query, args...).Scan(&t.ID, &t.Name); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (q thingQuery) All(ctx context.Context, exec boil.ContextExecutor) (thingSlice, error) {
+	query, args := queries.BuildQuery(q.Query)
+	rows, err := exec.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var all thingSlice
+	for rows.Next() {
+		t := &thing{}
+		if err := rows.Scan(&t.ID, &t.Name); err != nil {
+			return nil, err
+		}
+
+		all = append(all, t)
+	}
+
+	return all, rows.Err()
+}
+
+func (q thingQuery) Count(ctx context.Context, exec boil.ContextExecutor) (int64, error) {
+	queries.SetSelect(q.Query, []string{"COUNT(*)"})
+	query, args := queries.BuildQuery(q.Query)
+	var n int64
+	err := exec.QueryRowContext(ctx, query, args...).Scan(&n)
+	return n, err
+}
+
+func (q thingQuery) Exists(ctx context.Context, exec boil.ContextExecutor) (bool, error) {
+	n, err := q.Count(ctx, exec)
+	return n > 0, err
+}
+
+func (t *thing) Insert(ctx context.Context, exec boil.ContextExecutor, _ boil.Columns) error {
+	res, err := exec.ExecContext(ctx, "INSERT INTO `things` (`name`) VALUES (?)", t.Name)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	t.ID = int(id)
+	return err
+}
+
+func (t *thing) Update(ctx context.Context, exec boil.ContextExecutor, columns boil.Columns) (int64, error) {
+	if !columns.IsWhitelist() || len(columns.Cols) != 1 || columns.Cols[0] != "name" {
+		return 0, sql.ErrConnDone
+	}
+
+	res, err := exec.ExecContext(ctx, "UPDATE `things` SET `name` = ? WHERE `id` = ?", t.Name, t.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (t *thing) Delete(ctx context.Context, exec boil.ContextExecutor) (int64, error) {
+	res, err := exec.ExecContext(ctx, "DELETE FROM `things` WHERE `id` = ?", t.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func TestRepository_Read(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	defer db.Close()
+
+	repo := NewRepository[*thing, thingSlice](db, things, "id")
+
+	mock.ExpectQuery("SELECT \\* FROM `things` WHERE \\(id = \\?\\) LIMIT 1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a"))
+	got, err := repo.Get(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, &thing{ID: 1, Name: "a"}, got)
+
+	mock.ExpectQuery("SELECT \\* FROM `things` WHERE \\(id = \\?\\) LIMIT 1").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+	_, err = repo.Get(context.Background(), 2)
+	assert.True(t, errorx.Is(err, errorx.NotFound))
+
+	mock.ExpectQuery("SELECT \\* FROM `things` ORDER BY name").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b"))
+	all, err := repo.List(context.Background(), qm.OrderBy("name"))
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM `things`").WillReturnRows(sqlmock.NewRows([]string{"n"}).AddRow(2))
+	n, err := repo.Count(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_WriteJoinsTransaction(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	defer db.Close()
+
+	repo := NewRepository[*thing, thingSlice](db, things, "id")
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `things`").WithArgs("a").WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectExec("UPDATE `things`").WithArgs("b", 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM `things`").WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := db.Atomic(context.Background(), nil, func(ctx context.Context, _ *sql.Tx) error {
+		th := &thing{Name: "a"}
+		if err := repo.Create(ctx, th); err != nil {
+			return err
+		}
+
+		th.Name = "b"
+		if err := repo.Update(ctx, th, "name"); err != nil {
+			return err
+		}
+
+		return repo.Delete(ctx, th)
+	})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_DeleteMissingAndUpsert(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	defer db.Close()
+
+	repo := NewRepository[*thing, thingSlice](db, things, "id")
+	mock.ExpectExec("DELETE FROM `things`").WithArgs(9).WillReturnResult(sqlmock.NewResult(0, 0))
+	err := repo.Delete(context.Background(), &thing{ID: 9})
+	assert.True(t, errorx.Is(err, errorx.NotFound))
+
+	err = repo.Upsert(context.Background(), &thing{ID: 9})
+	require.ErrorIs(t, err, ErrUpsertNotSupported)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// pgThing mimics a sqlboiler model generated for Postgres, whose Upsert takes the
+// conflict columns and the options of its package.
+type pgThing struct {
+	ID       int    `boil:"id" json:"id"`
+	TenantID string `boil:"tenant_id" json:"tenant_id"`
+	Name     string `boil:"name" json:"name"`
+}
+
+type pgUpsertOption func(*string)
+
+func (t *pgThing) Insert(context.Context, boil.ContextExecutor, boil.Columns) error {
+	return nil
+}
+
+func (t *pgThing) Update(context.Context, boil.ContextExecutor, boil.Columns) (int64, error) {
+	return 0, nil
+}
+
+func (t *pgThing) Upsert(ctx context.Context, exec boil.ContextExecutor, updateOnConflict bool, conflictColumns []string,
+	updateColumns, _ boil.Columns, _ ...pgUpsertOption,
+) error {
+	if !updateOnConflict || len(conflictColumns) == 0 {
+		conflictColumns = []string{"id"}
+	}
+
+	set := "name = EXCLUDED.name"
+	if updateColumns.IsWhitelist() {
+		set = updateColumns.Cols[0] + " = EXCLUDED." + updateColumns.Cols[0]
+	}
+
+	_, err := exec.ExecContext(ctx, "INSERT INTO things (id, tenant_id, name) VALUES ($1, $2, $3) ON CONFLICT ("+
+		strings.Join(conflictColumns, ", ")+") DO UPDATE SET "+set, t.ID, t.TenantID, t.Name)
+	return err
+}
+
+type pgThingQuery struct {
+	thingQuery
+}
+
+func pgThings(mods ...qm.QueryMod) pgThingQuery {
+	return pgThingQuery{things(mods...)}
+}
+
+func (q pgThingQuery) One(context.Context, boil.ContextExecutor) (*pgThing, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (q pgThingQuery) All(context.Context, boil.ContextExecutor) ([]*pgThing, error) {
+	return nil, nil
+}
+
+func TestRepository_UpsertPostgres(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	defer db.Close()
+
+	repo := NewRepository[*pgThing, []*pgThing](db, pgThings, "id")
+	mock.ExpectExec(regexp.QuoteMeta("ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name")).
+		WithArgs(1, "", "a").WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, repo.Upsert(context.Background(), &pgThing{ID: 1, Name: "a"}, "name"))
+
+	scoped := NewRepository[*pgThing, []*pgThing](db, pgThings, "id", WithTenantScope(),
+		WithConflictColumns(TenantColumn, "name"))
+	ctx := tenantkit.WithTenant(context.Background(), "acme")
+	mock.ExpectExec(regexp.QuoteMeta("ON CONFLICT (tenant_id, name) DO UPDATE")).
+		WithArgs(2, "acme", "b").WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, scoped.Upsert(ctx, &pgThing{ID: 2, TenantID: "other", Name: "b"}))
+
+	// Conflicting on the primary key alone could update the row of another tenant
+	unscoped := NewRepository[*pgThing, []*pgThing](db, pgThings, "id", WithTenantScope())
+	err := unscoped.Upsert(ctx, &pgThing{ID: 3, Name: "c"})
+	require.ErrorIs(t, err, ErrUpsertTenantConflict)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,130 @@
+package dbkit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+const (
+	_mysqlLockWaitTimeout = 1205
+	_mysqlDeadlock        = 1213
+
+	_pgSerializationFailure = "40001"
+	_pgDeadlockDetected     = "40P01"
+
+	_defaultRetryAttempts   = 3
+	_defaultRetryBaseDelay  = 20 * time.Millisecond
+	_defaultRetryMaxBackoff = time.Second
+)
+
+// RetryPolicy controls how AtomicRetry re-runs transactions that failed with a transient error.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, the first one included
+	MaxAttempts int
+	// BaseBackoff is doubled on every attempt and capped at MaxBackoff,
+	// the actual wait is a random duration up to that value
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// Retryable classifies the errors worth a retry, defaults to IsRetryable
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy retries deadlocks and serialization failures up to 3 attempts.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: _defaultRetryAttempts,
+		BaseBackoff: _defaultRetryBaseDelay,
+		MaxBackoff:  _defaultRetryMaxBackoff,
+		Retryable:   IsRetryable,
+	}
+}
+
+// IsRetryable reports whether err is a transient transaction failure of MySQL or Postgres.
+func IsRetryable(err error) bool {
+	return IsMySQLRetryable(err) || IsPostgresRetryable(err)
+}
+
+// IsMySQLRetryable reports whether err is a MySQL deadlock or lock wait timeout.
+func IsMySQLRetryable(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+
+	return mysqlErr.Number == _mysqlDeadlock || mysqlErr.Number == _mysqlLockWaitTimeout
+}
+
+// IsPostgresRetryable reports whether err is a Postgres serialization failure or deadlock.
+func IsPostgresRetryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+
+	return pqErr.Code == _pgSerializationFailure || pqErr.Code == _pgDeadlockDetected
+}
+
+// AtomicRetry works like Atomic but re-runs fn on a fresh transaction when it fails
+// with an error classified as retryable by policy. fn must therefore be safe to run
+// more than once. Nested calls join the outer transaction without retrying, the
+// outermost AtomicRetry retries the whole transaction.
+func (c *SQLConn) AtomicRetry(ctx context.Context, opts *sql.TxOptions, policy RetryPolicy, fn TxFunc) error {
+	if _, ok := c.ownTx(ctx); ok {
+		return c.Atomic(ctx, opts, fn)
+	}
+
+	policy = policy.withDefaults()
+	for attempt := 1; ; attempt++ {
+		err := c.Atomic(ctx, opts, fn)
+		if err == nil || attempt >= policy.MaxAttempts || !policy.Retryable(err) {
+			return err
+		}
+
+		backoff := policy.backoff(attempt)
+		c.logger.Warn("transaction failed with a transient error, retrying",
+			"error", err, "attempt", attempt, "max_attempts", policy.MaxAttempts, "backoff", backoff.String())
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	def := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = def.MaxAttempts
+	}
+
+	if p.BaseBackoff <= 0 {
+		p.BaseBackoff = def.BaseBackoff
+	}
+
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = def.MaxBackoff
+	}
+
+	if p.Retryable == nil {
+		p.Retryable = def.Retryable
+	}
+
+	return p
+}
+
+// backoff returns a random wait up to the exponential backoff of attempt (full jitter).
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	ceiling := p.BaseBackoff << (attempt - 1)
+	if ceiling <= 0 || ceiling > p.MaxBackoff {
+		ceiling = p.MaxBackoff
+	}
+
+	return rand.N(ceiling) + 1
+}
@@ -0,0 +1,106 @@
+package dbkit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "mysql deadlock", err: &mysql.MySQLError{Number: 1213}, expected: true},
+		{name: "mysql lock wait timeout", err: &mysql.MySQLError{Number: 1205}, expected: true},
+		{name: "mysql duplicate entry", err: &mysql.MySQLError{Number: 1062}, expected: false},
+		{name: "postgres serialization failure", err: &pq.Error{Code: "40001"}, expected: true},
+		{name: "postgres deadlock", err: &pq.Error{Code: "40P01"}, expected: true},
+		{name: "postgres unique violation", err: &pq.Error{Code: "23505"}, expected: false},
+		{name: "wrapped", err: fmt.Errorf("insert user: %w", &mysql.MySQLError{Number: 1213}), expected: true},
+		{name: "other", err: errors.New("boom"), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, IsRetryable(tt.err))
+		})
+	}
+}
+
+func TestAtomicRetry_RetriesTransientErrors(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE accounts").WillReturnError(&mysql.MySQLError{Number: 1213, Message: "Deadlock found"})
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE accounts").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	attempts := 0
+	policy := RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond}
+	err := db.AtomicRetry(context.Background(), nil, policy, func(ctx context.Context, txn *sql.Tx) error {
+		attempts++
+		_, err := txn.ExecContext(ctx, "UPDATE accounts SET balance = balance - 1")
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAtomicRetry_StopsOnPermanentErrorsAndMaxAttempts(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	defer db.Close()
+
+	errPermanent := errors.New("permanent")
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	attempts := 0
+	err := db.AtomicRetry(context.Background(), nil, RetryPolicy{}, func(context.Context, *sql.Tx) error {
+		attempts++
+		return errPermanent
+	})
+	require.ErrorIs(t, err, errPermanent)
+	assert.Equal(t, 1, attempts)
+
+	errSerialization := &pq.Error{Code: "40001"}
+	for range 2 {
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+	}
+
+	attempts = 0
+	policy := RetryPolicy{MaxAttempts: 2, BaseBackoff: time.Millisecond}
+	err = db.AtomicRetry(context.Background(), nil, policy, func(context.Context, *sql.Tx) error {
+		attempts++
+		return errSerialization
+	})
+	require.ErrorIs(t, err, errSerialization)
+	assert.Equal(t, 2, attempts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{BaseBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}.withDefaults()
+
+	for attempt := 1; attempt <= 10; attempt++ {
+		ceiling := min(p.BaseBackoff<<(attempt-1), p.MaxBackoff)
+		backoff := p.backoff(attempt)
+		assert.Positive(t, backoff)
+		assert.LessOrEqual(t, backoff, ceiling)
+	}
+}
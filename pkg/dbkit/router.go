@@ -0,0 +1,297 @@
+package dbkit
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/wasay-usmani/go-boilerplate/pkg/logkit"
+)
+
+const (
+	_defaultHealthCheckInterval = 5 * time.Second
+	_defaultHealthCheckTimeout  = time.Second
+)
+
+var (
+	_ boil.ContextExecutor = (*Router)(nil)
+	_ boil.ContextBeginner = (*Router)(nil)
+)
+
+// Router routes queries between a primary and its read replicas. It implements
+// boil.ContextExecutor, so it can be passed to sqlboiler wherever a db is expected.
+//
+// Exec calls, locking reads and non read-only statements go to the primary, as do
+// transactions started with BeginTx or Atomic. Queries run with a context carrying
+// an Atomic transaction run in that transaction. Reads are balanced round-robin over
+// the healthy replicas and fall back to the primary when every replica is down.
+// Use WithPrimary to read your own writes.
+type Router struct {
+	primary  *SQLConn
+	replicas []*replica
+	next     atomic.Uint64
+	logger   logkit.Logger
+
+	healthCheckInterval time.Duration
+	healthCheckTimeout  time.Duration
+	stop                chan struct{}
+	stopOnce            sync.Once
+	wg                  sync.WaitGroup
+}
+
+// RouterOption customizes a Router.
+type RouterOption func(*Router)
+
+type replica struct {
+	conn    *SQLConn
+	healthy atomic.Bool
+}
+
+type primaryCtxKey struct{}
+
+// WithHealthCheckInterval sets how often replicas are pinged, defaults to 5 seconds.
+func WithHealthCheckInterval(d time.Duration) RouterOption {
+	return func(r *Router) {
+		r.healthCheckInterval = d
+	}
+}
+
+// WithHealthCheckTimeout bounds each replica ping, defaults to a second.
+func WithHealthCheckTimeout(d time.Duration) RouterOption {
+	return func(r *Router) {
+		r.healthCheckTimeout = d
+	}
+}
+
+// WithPrimary forces the queries run with the returned context to the primary.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryCtxKey{}, true)
+}
+
+// NewRouter returns a router over primary and replicas, and starts the replica
+// health checks. Close stops them and closes every connection.
+func NewRouter(primary *SQLConn, replicas []*SQLConn, l logkit.Logger, opts ...RouterOption) *Router {
+	r := &Router{
+		primary:             primary,
+		logger:              l,
+		healthCheckInterval: _defaultHealthCheckInterval,
+		healthCheckTimeout:  _defaultHealthCheckTimeout,
+		stop:                make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	for _, conn := range replicas {
+		rep := &replica{conn: conn}
+		rep.healthy.Store(true)
+		r.replicas = append(r.replicas, rep)
+	}
+
+	if len(r.replicas) > 0 {
+		r.wg.Add(1)
+		go r.healthCheck()
+	}
+
+	return r
+}
+
+// Primary returns the primary connection.
+func (r *Router) Primary() *SQLConn {
+	return r.primary
+}
+
+// Replica returns the next healthy replica, or the primary when none is healthy.
+func (r *Router) Replica() *SQLConn {
+	n := len(r.replicas)
+	start := r.next.Add(1)
+	for i := range n {
+		rep := r.replicas[(start+uint64(i))%uint64(n)]
+		if rep.healthy.Load() {
+			return rep.conn
+		}
+	}
+
+	return r.primary
+}
+
+// Executor returns the transaction of the primary carried in ctx, or the router itself
+// outside a transaction.
+func (r *Router) Executor(ctx context.Context) boil.ContextExecutor {
+	if state, ok := r.primary.ownTx(ctx); ok {
+		return state.txn
+	}
+
+	return r
+}
+
+func (r *Router) Exec(query string, args ...any) (sql.Result, error) {
+	return r.ExecContext(context.Background(), query, args...)
+}
+
+func (r *Router) Query(query string, args ...any) (*sql.Rows, error) {
+	return r.QueryContext(context.Background(), query, args...)
+}
+
+func (r *Router) QueryRow(query string, args ...any) *sql.Row {
+	return r.QueryRowContext(context.Background(), query, args...)
+}
+
+func (r *Router) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return r.primary.Executor(ctx).ExecContext(ctx, query, args...)
+}
+
+func (r *Router) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if state, ok := r.primary.ownTx(ctx); ok {
+		return state.txn.QueryContext(ctx, query, args...)
+	}
+
+	conn := r.route(ctx, query)
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil && r.markDown(ctx, conn, err) {
+		return r.primary.QueryContext(ctx, query, args...)
+	}
+
+	return rows, err
+}
+
+func (r *Router) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if state, ok := r.primary.ownTx(ctx); ok {
+		return state.txn.QueryRowContext(ctx, query, args...)
+	}
+
+	conn := r.route(ctx, query)
+	row := conn.QueryRowContext(ctx, query, args...)
+	if err := row.Err(); err != nil && r.markDown(ctx, conn, err) {
+		return r.primary.QueryRowContext(ctx, query, args...)
+	}
+
+	return row
+}
+
+// BeginTx starts a transaction on the primary.
+func (r *Router) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return r.primary.BeginTx(ctx, opts)
+}
+
+// Atomic runs fn in a transaction on the primary, see SQLConn.Atomic.
+func (r *Router) Atomic(ctx context.Context, opts *sql.TxOptions, fn TxFunc) error {
+	return r.primary.Atomic(ctx, opts, fn)
+}
+
+// AtomicRetry runs fn in a transaction on the primary, see SQLConn.AtomicRetry.
+func (r *Router) AtomicRetry(ctx context.Context, opts *sql.TxOptions, policy RetryPolicy, fn TxFunc) error {
+	return r.primary.AtomicRetry(ctx, opts, policy, fn)
+}
+
+// Close stops the health checks and closes the primary and replica connections.
+// It is safe to call more than once.
+func (r *Router) Close() error {
+	r.stopOnce.Do(func() { close(r.stop) })
+	r.wg.Wait()
+
+	errs := []error{r.primary.Close()}
+	for _, rep := range r.replicas {
+		errs = append(errs, rep.conn.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+func (r *Router) route(ctx context.Context, query string) *SQLConn {
+	if forced, _ := ctx.Value(primaryCtxKey{}).(bool); forced || !isReadQuery(query) {
+		return r.primary
+	}
+
+	return r.Replica()
+}
+
+// markDown marks conn unhealthy when it is a replica failing with a broken
+// connection or a network error, so the query can be retried on the primary.
+// A canceled or expired ctx is the caller's doing, not the replica's: context
+// errors also implement net.Error, so they are ruled out first.
+func (r *Router) markDown(ctx context.Context, conn *SQLConn, err error) bool {
+	if conn == r.primary || ctx.Err() != nil ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var netErr net.Error
+	if !errors.Is(err, driver.ErrBadConn) && !errors.As(err, &netErr) {
+		return false
+	}
+
+	for _, rep := range r.replicas {
+		if rep.conn == conn && rep.healthy.Swap(false) {
+			r.logger.Error("db replica is down, routing reads to the remaining replicas", err)
+		}
+	}
+
+	return true
+}
+
+func (r *Router) healthCheck() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			for i, rep := range r.replicas {
+				r.checkReplica(i, rep)
+			}
+		}
+	}
+}
+
+func (r *Router) checkReplica(i int, rep *replica) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.healthCheckTimeout)
+	defer cancel()
+
+	err := rep.conn.PingContext(ctx)
+	healthy := err == nil
+	if rep.healthy.Swap(healthy) == healthy {
+		return
+	}
+
+	if healthy {
+		r.logger.Info("db replica is back up", "replica", i)
+		return
+	}
+
+	r.logger.Error("db replica is down, routing reads to the remaining replicas", err, "replica", i)
+}
+
+// isReadQuery reports whether query is a read-only statement that can run on a replica.
+func isReadQuery(query string) bool {
+	q := strings.ToUpper(strings.TrimLeft(query, " \t\r\n("))
+	fields := strings.Fields(q)
+	if len(fields) == 0 {
+		return false
+	}
+
+	if !slices.Contains([]string{"SELECT", "SHOW", "DESCRIBE", "DESC", "EXPLAIN"}, fields[0]) {
+		return false
+	}
+
+	for _, lock := range []string{" FOR UPDATE", " FOR SHARE", " FOR NO KEY UPDATE", " FOR KEY SHARE", " LOCK IN SHARE MODE"} {
+		if strings.Contains(q, lock) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,157 @@
+package dbkit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestConn(t *testing.T) (*SQLConn, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+
+	return &SQLConn{db, testLogger}, mock
+}
+
+func TestRouter_Routing(t *testing.T) {
+	primary, primaryMock := newTestConn(t)
+	replica1, replica1Mock := newTestConn(t)
+	replica2, replica2Mock := newTestConn(t)
+	router := NewRouter(primary, []*SQLConn{replica1, replica2}, testLogger, WithHealthCheckInterval(time.Hour))
+	defer router.Close()
+
+	ctx := context.Background()
+	rows := func() *sqlmock.Rows { return sqlmock.NewRows([]string{"id"}).AddRow(1) }
+
+	// Reads are balanced over the replicas
+	replica2Mock.ExpectQuery("SELECT id FROM users").WillReturnRows(rows())
+	replica1Mock.ExpectQuery("SELECT id FROM users").WillReturnRows(rows())
+	for range 2 {
+		r, err := router.QueryContext(ctx, "SELECT id FROM users")
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+	}
+
+	// Writes, locking reads and forced reads go to the primary
+	primaryMock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(1, 1))
+	primaryMock.ExpectQuery("SELECT id FROM users WHERE id = \\? FOR UPDATE").WillReturnRows(rows())
+	primaryMock.ExpectQuery("SELECT id FROM users").WillReturnRows(rows())
+
+	_, err := router.ExecContext(ctx, "INSERT INTO users (id) VALUES (?)", 1)
+	require.NoError(t, err)
+
+	var id int
+	require.NoError(t, router.QueryRowContext(ctx, "SELECT id FROM users WHERE id = ? FOR UPDATE", 1).Scan(&id))
+	require.NoError(t, router.QueryRowContext(WithPrimary(ctx), "SELECT id FROM users").Scan(&id))
+
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+	assert.NoError(t, replica1Mock.ExpectationsWereMet())
+	assert.NoError(t, replica2Mock.ExpectationsWereMet())
+}
+
+func TestRouter_FallsBackToPrimary(t *testing.T) {
+	primary, primaryMock := newTestConn(t)
+	replica, replicaMock := newTestConn(t)
+
+	replicaMock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	router := NewRouter(primary, []*SQLConn{replica}, testLogger, WithHealthCheckInterval(10*time.Millisecond))
+	defer router.Close()
+
+	assert.Eventually(t, func() bool {
+		return router.Replica() == primary
+	}, time.Second, 5*time.Millisecond)
+
+	primaryMock.ExpectQuery("SELECT id FROM users").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	var id int
+	require.NoError(t, router.QueryRowContext(context.Background(), "SELECT id FROM users").Scan(&id))
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+}
+
+func TestIsReadQuery(t *testing.T) {
+	tests := map[string]bool{
+		"SELECT * FROM users":                         true,
+		"  (select id from users) union (select 1)":   true,
+		"SELECT\n* FROM users":                        true,
+		"EXPLAIN SELECT * FROM users":                 true,
+		"SELECT * FROM users FOR UPDATE":              false,
+		"select * from users lock in share mode":      false,
+		"INSERT INTO users (id) VALUES (1) RETURNING": false,
+		"WITH d AS (DELETE FROM users) SELECT 1":      false,
+		"":                                            false,
+	}
+
+	for query, expected := range tests {
+		assert.Equal(t, expected, isReadQuery(query), query)
+	}
+}
+
+func TestRouter_QueriesJoinContextTransaction(t *testing.T) {
+	primary, primaryMock := newTestConn(t)
+	replica, replicaMock := newTestConn(t)
+	router := NewRouter(primary, []*SQLConn{replica}, testLogger, WithHealthCheckInterval(time.Hour))
+	defer router.Close()
+
+	primaryMock.ExpectBegin()
+	primaryMock.ExpectQuery("SELECT id FROM users").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	primaryMock.ExpectCommit()
+
+	err := router.Atomic(context.Background(), nil, func(ctx context.Context, _ *sql.Tx) error {
+		var id int
+		return router.QueryRowContext(ctx, "SELECT id FROM users").Scan(&id)
+	})
+	require.NoError(t, err)
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+}
+
+func TestRouter_QueryRowFallsBackOnBrokenReplica(t *testing.T) {
+	primary, primaryMock := newTestConn(t)
+	replica, replicaMock := newTestConn(t)
+	router := NewRouter(primary, []*SQLConn{replica}, testLogger, WithHealthCheckInterval(time.Hour))
+	defer router.Close()
+
+	replicaMock.ExpectQuery("SELECT id FROM users").WillReturnError(&net.OpError{Op: "read", Err: errors.New("connection reset")})
+	primaryMock.ExpectQuery("SELECT id FROM users").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	var id int
+	require.NoError(t, router.QueryRowContext(context.Background(), "SELECT id FROM users").Scan(&id))
+	assert.Equal(t, 1, id)
+	assert.Same(t, primary, router.Replica())
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+}
+
+func TestRouter_ContextErrorsKeepReplicaUp(t *testing.T) {
+	primary, primaryMock := newTestConn(t)
+	replica, replicaMock := newTestConn(t)
+	router := NewRouter(primary, []*SQLConn{replica}, testLogger, WithHealthCheckInterval(time.Hour))
+	defer router.Close()
+
+	replicaMock.ExpectQuery("SELECT id FROM users").WillReturnError(context.DeadlineExceeded)
+	_, err := router.QueryContext(context.Background(), "SELECT id FROM users")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.Same(t, replica, router.Replica())
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+}
+
+func TestRouter_CloseTwice(t *testing.T) {
+	primary, primaryMock := newTestConn(t)
+	replica, replicaMock := newTestConn(t)
+	router := NewRouter(primary, []*SQLConn{replica}, testLogger, WithHealthCheckInterval(time.Hour))
+
+	primaryMock.ExpectClose()
+	replicaMock.ExpectClose()
+	require.NoError(t, router.Close())
+	assert.NotPanics(t, func() { _ = router.Close() })
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aarondl/sqlboiler/v4/boil"
 	"github.com/wasay-usmani/go-boilerplate/pkg/logkit"
 )
 
@@ -44,7 +45,64 @@ func (c *SQLConn) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, er
 	return txn, nil
 }
 
-func (c *SQLConn) Atomic(ctx context.Context, opts *sql.TxOptions, fn func(txn *sql.Tx) error) (err error) {
+// TxFunc is run by Atomic. ctx carries the transaction, so Atomic calls made
+// with it, directly or through other repository methods, join the transaction.
+type TxFunc func(ctx context.Context, txn *sql.Tx) error
+
+type txCtxKey struct{}
+
+// txState is the transaction carried in the context, the connection that started it
+// and its number of open savepoints. parent is the transaction of another connection
+// that was carried in the context when it started.
+type txState struct {
+	conn      *SQLConn
+	txn       *sql.Tx
+	savepoint int
+	parent    *txState
+}
+
+// TxFromContext returns the transaction of the innermost Atomic carried in ctx.
+func TxFromContext(ctx context.Context) (*sql.Tx, bool) {
+	state, ok := ctx.Value(txCtxKey{}).(*txState)
+	if !ok {
+		return nil, false
+	}
+
+	return state.txn, true
+}
+
+// ownTx returns the transaction carried in ctx that was started on c.
+func (c *SQLConn) ownTx(ctx context.Context) (*txState, bool) {
+	state, _ := ctx.Value(txCtxKey{}).(*txState)
+	for ; state != nil; state = state.parent {
+		if state.conn == c {
+			return state, true
+		}
+	}
+
+	return nil, false
+}
+
+// Executor returns the transaction carried in ctx, or the connection pool outside a
+// transaction or when the transaction was started on another connection.
+func (c *SQLConn) Executor(ctx context.Context) boil.ContextExecutor {
+	if state, ok := c.ownTx(ctx); ok {
+		return state.txn
+	}
+
+	return c.DB
+}
+
+// Atomic runs fn in a transaction, committed when fn returns nil and rolled back otherwise.
+// When ctx already carries a transaction started on c, fn runs in a savepoint of it
+// instead: an error only rolls back to the savepoint and only the outermost Atomic
+// commits. opts are ignored for nested calls. A transaction started on another
+// connection is not joined, fn runs in a new transaction of c.
+func (c *SQLConn) Atomic(ctx context.Context, opts *sql.TxOptions, fn TxFunc) (err error) {
+	if state, ok := c.ownTx(ctx); ok {
+		return c.savepoint(ctx, state, fn)
+	}
+
 	txn, err := c.BeginTx(ctx, opts)
 	if err != nil {
 		return err
@@ -59,7 +117,56 @@ func (c *SQLConn) Atomic(ctx context.Context, opts *sql.TxOptions, fn func(txn *
 		err = c.resolveTxn(txn, err)
 	}()
 
-	if err := fn(txn); err != nil {
+	parent, _ := ctx.Value(txCtxKey{}).(*txState)
+	if err := fn(context.WithValue(ctx, txCtxKey{}, &txState{conn: c, txn: txn, parent: parent}), txn); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// savepoint runs fn in a savepoint of the transaction carried in ctx. The syntax
+// is shared by MySQL and Postgres.
+func (c *SQLConn) savepoint(ctx context.Context, state *txState, fn TxFunc) (err error) {
+	state.savepoint++
+	name := fmt.Sprintf("sp_%d", state.savepoint)
+	defer func() {
+		state.savepoint--
+	}()
+
+	if _, err := state.txn.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		c.logger.Error("create savepoint error", err, "savepoint", name)
+		return fmt.Errorf("create savepoint failure %w", err)
+	}
+
+	defer func() {
+		if e := recover(); e != nil {
+			err = c.resolveSavepoint(ctx, state.txn, name, fmt.Errorf("panic occurred, cause: %v", e))
+			return
+		}
+
+		err = c.resolveSavepoint(ctx, state.txn, name, err)
+	}()
+
+	if err := fn(ctx, state.txn); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *SQLConn) resolveSavepoint(ctx context.Context, txn *sql.Tx, name string, err error) error {
+	if err != nil {
+		if _, rollbackErr := txn.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rollbackErr != nil {
+			c.logger.Error("savepoint rollback failed", rollbackErr, "savepoint", name)
+			return errors.Join(err, rollbackErr)
+		}
+
+		return err
+	}
+
+	if _, err := txn.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		c.logger.Error("savepoint release failed", err, "savepoint", name)
 		return err
 	}
 
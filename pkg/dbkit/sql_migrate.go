@@ -1,92 +1,757 @@
 package dbkit
 
 import (
+	"context"
 	"database/sql"
 	"embed"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
 	migrate "github.com/rubenv/sql-migrate"
 	"github.com/wasay-usmani/go-boilerplate/pkg/logkit"
 )
 
 const (
-	_migrations = "migrations"
-	_schema     = "schema"
-	_app        = "app"
+	// SchemaMigrations is the migration set applied with the superuser connection.
+	SchemaMigrations = "schema"
+	// AppMigrations is the migration set applied with the app connection.
+	AppMigrations = "app"
+
+	_migrations         = "migrations"
+	_defaultLockTimeout = time.Minute
+
+	// Postgres lock_not_available, raised when lock_timeout expires
+	_pgLockNotAvailable = "55P03"
+	// MySQL ER_BAD_DB_ERROR, raised when connecting to a missing database
+	_mysqlBadDB = 1049
 )
 
+var (
+	// ErrMigrationLocked is returned when another process holds the migration lock.
+	ErrMigrationLocked = errors.New("migrations are locked by another process")
+	// ErrUnknownMigration is returned when a target migration is not part of the set.
+	ErrUnknownMigration = errors.New("unknown migration")
+)
+
+// MigrationStatus is the state of a single migration of the schema or app set.
+type MigrationStatus struct {
+	Set       string
+	ID        string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator runs the schema migrations with the superuser connection and the
+// app migrations with the app connection. Both sets track their state in their
+// own table, suffixed with the db schema, and are guarded by a named lock so
+// concurrent replicas apply them only once.
+//
+// The db schema is created when missing, before any migration runs: a database
+// on MySQL, a schema on Postgres. On Postgres it is also set as the search_path
+// of the connections and holds both migration tables. Migrations of the mysql and
+// postgres subdirectories of a set only run on their dialect.
+//
+// Dry runs and Status only read the database: they create neither the db schema nor
+// the migration tables and take no lock, every migration is pending when those are
+// missing.
+type Migrator struct {
+	superUserDBURL string
+	appDBURL       string
+	dbSchema       string
+	schemaAssets   embed.FS
+	appAssets      embed.FS
+	logger         logkit.Logger
+
+	dialect     Dialect
+	dryRun      io.Writer
+	lockTimeout time.Duration
+	connOpts    []Option
+}
+
+// MigratorOption customizes a Migrator.
+type MigratorOption func(*Migrator)
+
+type migrationSet struct {
+	name    string
+	dbURL   string
+	dialect Dialect
+	assets  fs.FS
+	// readOnly inspects the set without creating the db schema, tables or lock
+	readOnly bool
+}
+
+// WithDialect sets the dialect of both migration sets instead of detecting it from their URLs.
+func WithDialect(d Dialect) MigratorOption {
+	return func(m *Migrator) {
+		m.dialect = d
+	}
+}
+
+// WithConnOptions sets the pool and connect options of the migration connections,
+// e.g. WithConnectRetry to wait for a database starting alongside the migrations.
+func WithConnOptions(opts ...Option) MigratorOption {
+	return func(m *Migrator) {
+		m.connOpts = append(m.connOpts, opts...)
+	}
+}
+
+// WithDryRun writes the SQL of the planned migrations to w instead of executing it.
+func WithDryRun(w io.Writer) MigratorOption {
+	return func(m *Migrator) {
+		m.dryRun = w
+	}
+}
+
+// WithLockTimeout sets how long to wait for the migration lock, defaults to a minute.
+func WithLockTimeout(timeout time.Duration) MigratorOption {
+	return func(m *Migrator) {
+		m.lockTimeout = timeout
+	}
+}
+
+func NewMigrator(superUserDBURL, appDBURL, dbSchema string, l logkit.Logger, schemaAssets, appAssets embed.FS,
+	opts ...MigratorOption,
+) *Migrator {
+	m := &Migrator{
+		superUserDBURL: superUserDBURL,
+		appDBURL:       appDBURL,
+		dbSchema:       dbSchema,
+		schemaAssets:   schemaAssets,
+		appAssets:      appAssets,
+		logger:         l,
+		lockTimeout:    _defaultLockTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// ApplyMigrations applies all pending schema migrations, then all pending app migrations.
 func ApplyMigrations(superUserDBURL, appDBURL, dbSchema string, l logkit.Logger, schemaAssets, appAssets embed.FS) error {
-	// Init schema db connection
-	schemaDB, err := LoadMySQLConn(superUserDBURL, true, l)
+	return NewMigrator(superUserDBURL, appDBURL, dbSchema, l, schemaAssets, appAssets).Up(context.Background())
+}
+
+// Up applies all pending schema migrations, then all pending app migrations.
+func (m *Migrator) Up(ctx context.Context) error {
+	if err := m.migrate(ctx, m.schemaSet(), migrate.Up, 0, ""); err != nil {
+		return fmt.Errorf("failed to apply schema migrations, error: %w", err)
+	}
+
+	if err := m.migrate(ctx, m.appSet(), migrate.Up, 0, ""); err != nil {
+		return fmt.Errorf("failed to apply app migrations, error: %w", err)
+	}
+
+	return nil
+}
+
+// UpTo applies the pending migrations of set up to and including the migration id.
+func (m *Migrator) UpTo(ctx context.Context, set, id string) error {
+	s, err := m.set(set)
 	if err != nil {
-		return fmt.Errorf("failed to load superuser db, error: %w", err)
+		return err
 	}
 
-	defer func() {
-		if closeErr := schemaDB.Close(); closeErr != nil {
-			l.Error("failed to close schema db connection", closeErr)
-		}
-	}()
+	if err := m.migrate(ctx, s, migrate.Up, 0, id); err != nil {
+		return fmt.Errorf("failed to apply %s migrations up to %s, error: %w", set, id, err)
+	}
 
-	// Apply schema migrations
-	if err = applySchemaMigrations(schemaDB.DB, dbSchema, l, schemaAssets); err != nil {
-		return fmt.Errorf("failed to apply schema migrations, error: %w", err)
+	return nil
+}
+
+// Down rolls back the last max app migrations, all of them when max is 0.
+func (m *Migrator) Down(ctx context.Context, max int) error {
+	if err := m.migrate(ctx, m.appSet(), migrate.Down, max, ""); err != nil {
+		return fmt.Errorf("failed to rollback app migrations, error: %w", err)
 	}
 
-	// Init app db connection
-	appDB, err := LoadMySQLConn(appDBURL, true, l)
+	return nil
+}
+
+// DownTo rolls back the migrations of set applied after the migration id, which stays applied.
+func (m *Migrator) DownTo(ctx context.Context, set, id string) error {
+	s, err := m.set(set)
 	if err != nil {
-		return fmt.Errorf("failed to load sfpy_reporter, error: %w", err)
+		return err
 	}
 
-	defer func() {
-		if closeErr := appDB.Close(); closeErr != nil {
-			l.Error("failed to close app db connection", closeErr)
+	if err := m.migrate(ctx, s, migrate.Down, 0, id); err != nil {
+		return fmt.Errorf("failed to rollback %s migrations down to %s, error: %w", set, id, err)
+	}
+
+	return nil
+}
+
+// Redo rolls back the last app migration and applies it again.
+func (m *Migrator) Redo(ctx context.Context) error {
+	s := m.appSet()
+	err := m.withDB(ctx, s, func(db *sql.DB) error {
+		if m.dryRun != nil {
+			planned, err := m.plan(ctx, db, s, migrate.Down, 1)
+			if err != nil {
+				return err
+			}
+
+			if err := m.printPlan(s.name, migrate.Down, planned); err != nil {
+				return err
+			}
+
+			for _, p := range planned {
+				p.Queries = p.Up
+			}
+
+			return m.printPlan(s.name, migrate.Up, planned)
 		}
-	}()
 
-	// Apply app migrations
-	if err = applyAppMigrations(appDB.DB, dbSchema, l, appAssets); err != nil {
-		return fmt.Errorf("failed to apply app migrations, error: %w", err)
+		if err := m.exec(ctx, db, s, migrate.Down, 1); err != nil {
+			return err
+		}
+
+		return m.exec(ctx, db, s, migrate.Up, 1)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to redo app migration, error: %w", err)
 	}
 
 	return nil
 }
 
-func applySchemaMigrations(db *sql.DB, dbSchema string, l logkit.Logger, schemaAssets embed.FS) error {
-	migrationSource := &migrate.EmbedFileSystemMigrationSource{
-		FileSystem: schemaAssets,
-		Root:       "migrations",
+// Status lists every known schema and app migration, applied or pending.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	for _, s := range []migrationSet{m.schemaSet(), m.appSet()} {
+		s.readOnly = true
+		conn, err := m.open(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+
+		setStatuses, err := m.status(ctx, connDB(conn), s)
+		m.closeDB(conn, s.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s migrations status, error: %w", s.name, err)
+		}
+
+		statuses = append(statuses, setStatuses...)
 	}
 
-	migrationSet := &migrate.MigrationSet{
-		TableName: fmt.Sprintf("%s_%s_%s", _migrations, _schema, dbSchema),
+	return statuses, nil
+}
+
+// migrate plans max migrations of set in dir, or the migrations up to target when set,
+// then executes them or prints them when running dry.
+func (m *Migrator) migrate(ctx context.Context, s migrationSet, dir migrate.MigrationDirection, max int, target string) error {
+	return m.withDB(ctx, s, func(db *sql.DB) error {
+		if target != "" {
+			migrations, records, err := m.load(ctx, db, s)
+			if err != nil {
+				return err
+			}
+
+			if max, err = stepsTo(migrations, records, dir, target); err != nil {
+				return err
+			}
+
+			// max 0 means no limit, so stop here when the target is already reached
+			if max == 0 {
+				m.logger.Info(fmt.Sprintf("%s migrations already at %s", s.name, target))
+				return nil
+			}
+		}
+
+		if m.dryRun != nil {
+			planned, err := m.plan(ctx, db, s, dir, max)
+			if err != nil {
+				return err
+			}
+
+			return m.printPlan(s.name, dir, planned)
+		}
+
+		return m.exec(ctx, db, s, dir, max)
+	})
+}
+
+func (m *Migrator) exec(ctx context.Context, db *sql.DB, s migrationSet, dir migrate.MigrationDirection, max int) error {
+	ms, source := m.migrationSet(s)
+	n, err := ms.ExecMaxContext(ctx, db, string(s.dialect), source, dir, max)
+	if err != nil {
+		return err
 	}
 
-	n, err := migrationSet.Exec(db, "mysql", migrationSource, migrate.Up)
+	m.logger.Info(fmt.Sprintf("%s %d %s migrations", direction(dir), n, s.name))
+	return nil
+}
+
+// withDB opens a connection to the set db and holds the set lock while fn runs. Dry
+// runs take no lock and get a nil db when the MySQL database of the set is missing.
+func (m *Migrator) withDB(ctx context.Context, s migrationSet, fn func(db *sql.DB) error) error {
+	conn, err := m.open(ctx, s)
 	if err != nil {
 		return err
 	}
 
-	l.Info(fmt.Sprintf("applied %d schema migrations", n))
+	defer m.closeDB(conn, s.name)
+
+	if s.readOnly {
+		return fn(connDB(conn))
+	}
+
+	unlock, err := m.lock(ctx, conn.DB, s.dialect, m.tableName(s.name))
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	return fn(conn.DB)
+}
+
+// open connects to the set db and creates the db schema when missing: on MySQL a
+// database, created with the superuser connection of the schema set, on Postgres a
+// schema, set as the search_path of every connection of the pool. Read only sets
+// skip the creation and get a nil conn when their MySQL database is missing.
+func (m *Migrator) open(ctx context.Context, s migrationSet) (*SQLConn, error) {
+	conn, err := LoadSchemaConn(ctx, s.dbURL, s.dialect, m.dbSchema, true, m.logger, m.connOpts...)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if s.readOnly && errors.As(err, &mysqlErr) && mysqlErr.Number == _mysqlBadDB {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to load %s db, error: %w", s.name, err)
+	}
+
+	// The app connection of MySQL fails before this point when its database is missing
+	if s.readOnly || (s.dialect != Postgres && s.name != SchemaMigrations) {
+		return conn, nil
+	}
+
+	if err := m.ensureSchema(ctx, conn.DB, s.dialect); err != nil {
+		m.closeDB(conn, s.name)
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// ensureSchema creates the db schema, only when missing so it does not
+// require the CREATE privilege once the schema exists.
+func (m *Migrator) ensureSchema(ctx context.Context, db *sql.DB, d Dialect) error {
+	exists, create := "SELECT EXISTS (SELECT 1 FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?)", "CREATE DATABASE IF NOT EXISTS "
+	if d == Postgres {
+		exists, create = "SELECT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = $1)", "CREATE SCHEMA IF NOT EXISTS "
+	}
+
+	var found bool
+	if err := db.QueryRowContext(ctx, exists, m.dbSchema).Scan(&found); err != nil {
+		return fmt.Errorf("failed to check schema %s, error: %w", m.dbSchema, err)
+	}
+
+	if found {
+		return nil
+	}
+
+	if _, err := db.ExecContext(ctx, create+QuoteIdentifier(d, m.dbSchema)); err != nil {
+		return fmt.Errorf("failed to create schema %s, error: %w", m.dbSchema, err)
+	}
+
+	m.logger.Info("created schema", "schema", m.dbSchema)
 	return nil
 }
 
-func applyAppMigrations(db *sql.DB, dbSchema string, l logkit.Logger, appAssets embed.FS) error {
-	migrationSource := &migrate.EmbedFileSystemMigrationSource{
-		FileSystem: appAssets,
-		Root:       "migrations",
+// lock takes a named lock on a dedicated connection, so it is held until
+// unlock no matter which pooled connection runs the migrations.
+// MySQL uses GET_LOCK, Postgres a session advisory lock bounded by lock_timeout.
+func (m *Migrator) lock(ctx context.Context, db *sql.DB, d Dialect, name string) (unlock func(), err error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lock connection, error: %w", err)
 	}
 
-	migrationSet := &migrate.MigrationSet{
-		TableName: fmt.Sprintf("%s_%s_%s", _migrations, _app, dbSchema),
+	release := "SELECT RELEASE_LOCK(?)"
+	if d == Postgres {
+		release = "SELECT pg_advisory_unlock(hashtext($1))"
+		err = lockPostgres(ctx, conn, name, m.lockTimeout)
+	} else {
+		err = lockMySQL(ctx, conn, name, m.lockTimeout)
+	}
+
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return func() {
+		var released sql.NullBool
+		if err := conn.QueryRowContext(context.Background(), release, name).Scan(&released); err != nil {
+			m.logger.Error("failed to release migration lock", err, "lock", name)
+		}
+
+		_ = conn.Close()
+	}, nil
+}
+
+func lockMySQL(ctx context.Context, conn *sql.Conn, name string, timeout time.Duration) error {
+	var acquired sql.NullInt64
+	err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", name, int(timeout.Seconds())).Scan(&acquired)
+	if err != nil {
+		return fmt.Errorf("failed to acquire migration lock %s, error: %w", name, err)
 	}
 
-	n, err := migrationSet.Exec(db, "mysql", migrationSource, migrate.Up)
+	if acquired.Int64 != 1 {
+		return fmt.Errorf("%w: %s", ErrMigrationLocked, name)
+	}
+
+	return nil
+}
+
+func lockPostgres(ctx context.Context, conn *sql.Conn, name string, timeout time.Duration) error {
+	_, err := conn.ExecContext(ctx, fmt.Sprintf("SET lock_timeout = %d", timeout.Milliseconds()))
+	if err != nil {
+		return fmt.Errorf("failed to set lock timeout, error: %w", err)
+	}
+
+	_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock(hashtext($1))", name)
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == _pgLockNotAvailable {
+			return fmt.Errorf("%w: %s", ErrMigrationLocked, name)
+		}
+
+		return fmt.Errorf("failed to acquire migration lock %s, error: %w", name, err)
+	}
+
+	// Reset so the timeout does not leak into statements run on this connection
+	if _, err := conn.ExecContext(ctx, "SET lock_timeout = 0"); err != nil {
+		return fmt.Errorf("failed to reset lock timeout, error: %w", err)
+	}
+
+	return nil
+}
+
+func (m *Migrator) status(ctx context.Context, db *sql.DB, s migrationSet) ([]MigrationStatus, error) {
+	migrations, records, err := m.load(ctx, db, s)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]time.Time, len(records))
+	for _, r := range records {
+		applied[r.Id] = r.AppliedAt
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, mig := range migrations {
+		appliedAt, ok := applied[mig.Id]
+		statuses = append(statuses, MigrationStatus{
+			Set:       s.name,
+			ID:        mig.Id,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+
+	return statuses, nil
+}
+
+// load returns the known migrations of the set, sorted, and the applied migration
+// records, none when a read only set has no migration table yet.
+func (m *Migrator) load(ctx context.Context, db *sql.DB, s migrationSet) ([]*migrate.Migration, []*migrate.MigrationRecord, error) {
+	ms, source := m.migrationSet(s)
+	migrations, err := source.FindMigrations()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if found, err := m.tableExists(ctx, db, s); err != nil || !found {
+		return migrations, nil, err
+	}
+
+	records, err := ms.GetMigrationRecords(db, string(s.dialect))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return migrations, records, nil
+}
+
+// plan returns up to max migrations a run of the set in dir would execute, without
+// writing to the database. Every migration is pending when the table is missing.
+func (m *Migrator) plan(ctx context.Context, db *sql.DB, s migrationSet, dir migrate.MigrationDirection, max int,
+) ([]*migrate.PlannedMigration, error) {
+	found, err := m.tableExists(ctx, db, s)
+	if err != nil {
+		return nil, err
+	}
+
+	ms, source := m.migrationSet(s)
+	if found {
+		planned, _, err := ms.PlanMigration(db, string(s.dialect), source, dir, max)
+		return planned, err
+	}
+
+	if dir == migrate.Down {
+		return nil, nil
+	}
+
+	migrations, err := source.FindMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	if max > 0 && max < len(migrations) {
+		migrations = migrations[:max]
+	}
+
+	planned := make([]*migrate.PlannedMigration, 0, len(migrations))
+	for _, mig := range migrations {
+		planned = append(planned, &migrate.PlannedMigration{Migration: mig, DisableTransaction: mig.DisableTransactionUp, Queries: mig.Up})
+	}
+
+	return planned, nil
+}
+
+// tableExists reports whether the migration table of the set exists. It is always
+// true for sets allowed to create it, and false for a read only set without db.
+func (m *Migrator) tableExists(ctx context.Context, db *sql.DB, s migrationSet) (bool, error) {
+	if !s.readOnly {
+		return true, nil
+	}
+
+	if db == nil {
+		return false, nil
+	}
+
+	query, args := "SELECT EXISTS (SELECT 1 FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?)",
+		[]any{m.tableName(s.name)}
+	if s.dialect == Postgres {
+		query, args = "SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_schema = $1 AND table_name = $2)",
+			[]any{m.dbSchema, m.tableName(s.name)}
+	}
+
+	var found bool
+	if err := db.QueryRowContext(ctx, query, args...).Scan(&found); err != nil {
+		return false, fmt.Errorf("failed to check %s migration table, error: %w", s.name, err)
+	}
+
+	return found, nil
+}
+
+func (m *Migrator) printPlan(set string, dir migrate.MigrationDirection, planned []*migrate.PlannedMigration) error {
+	if len(planned) == 0 {
+		_, err := fmt.Fprintf(m.dryRun, "-- no %s migrations to apply\n", set)
 		return err
 	}
 
-	l.Info(fmt.Sprintf("applied %d app migrations", n))
+	for _, p := range planned {
+		if _, err := fmt.Fprintf(m.dryRun, "-- %s %s %s\n", set, dirName(dir), p.Id); err != nil {
+			return err
+		}
+
+		for _, q := range p.Queries {
+			if _, err := fmt.Fprintln(m.dryRun, strings.TrimSpace(q)); err != nil {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
+
+// connDB returns the pool of conn, nil for the missing database of a read only set.
+func connDB(conn *SQLConn) *sql.DB {
+	if conn == nil {
+		return nil
+	}
+
+	return conn.DB
+}
+
+func (m *Migrator) closeDB(conn *SQLConn, name string) {
+	if conn == nil {
+		return
+	}
+
+	if err := conn.Close(); err != nil {
+		m.logger.Error(fmt.Sprintf("failed to close %s db connection", name), err)
+	}
+}
+
+func (m *Migrator) set(name string) (migrationSet, error) {
+	switch name {
+	case SchemaMigrations:
+		return m.schemaSet(), nil
+	case AppMigrations:
+		return m.appSet(), nil
+	default:
+		return migrationSet{}, fmt.Errorf("unknown migration set %q", name)
+	}
+}
+
+func (m *Migrator) schemaSet() migrationSet {
+	return migrationSet{
+		name: SchemaMigrations, dbURL: m.superUserDBURL, dialect: m.dialectOf(m.superUserDBURL), assets: m.schemaAssets,
+		readOnly: m.dryRun != nil,
+	}
+}
+
+func (m *Migrator) appSet() migrationSet {
+	return migrationSet{
+		name: AppMigrations, dbURL: m.appDBURL, dialect: m.dialectOf(m.appDBURL), assets: m.appAssets,
+		readOnly: m.dryRun != nil,
+	}
+}
+
+func (m *Migrator) dialectOf(dbURL string) Dialect {
+	if m.dialect != "" {
+		return m.dialect
+	}
+
+	return DialectFromURL(dbURL)
+}
+
+func (m *Migrator) tableName(set string) string {
+	return fmt.Sprintf("%s_%s_%s", _migrations, set, m.dbSchema)
+}
+
+func (m *Migrator) migrationSet(s migrationSet) (*migrate.MigrationSet, migrate.MigrationSource) {
+	migrationSource := dialectMigrationSource{assets: s.assets, dialect: s.dialect}
+
+	migrationSet := &migrate.MigrationSet{
+		TableName:          m.tableName(s.name),
+		DisableCreateTable: s.readOnly,
+	}
+
+	if s.dialect == Postgres {
+		migrationSet.SchemaName = m.dbSchema
+	}
+
+	return migrationSet, migrationSource
+}
+
+// dialectMigrationSource reads the migrations of the migrations directory and of its
+// dialect subdirectory, e.g. migrations/postgres, for DDL differing between dialects.
+// A dialect migration replaces the shared migration of the same file name, if any.
+type dialectMigrationSource struct {
+	assets  fs.FS
+	dialect Dialect
+}
+
+func (s dialectMigrationSource) FindMigrations() ([]*migrate.Migration, error) {
+	migrations, err := s.find(_migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	root := path.Join(_migrations, string(s.dialect))
+	if _, err := fs.Stat(s.assets, root); errors.Is(err, fs.ErrNotExist) {
+		return migrations, nil
+	}
+
+	dialectMigrations, err := s.find(root)
+	if err != nil {
+		return nil, err
+	}
+
+	shared := make(map[string]int, len(migrations))
+	for i, mig := range migrations {
+		shared[mig.Id] = i
+	}
+
+	for _, mig := range dialectMigrations {
+		if i, ok := shared[mig.Id]; ok {
+			migrations[i] = mig
+			continue
+		}
+
+		migrations = append(migrations, mig)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Less(migrations[j]) })
+	return migrations, nil
+}
+
+func (s dialectMigrationSource) find(root string) ([]*migrate.Migration, error) {
+	dir, err := fs.Sub(s.assets, root)
+	if err != nil {
+		return nil, err
+	}
+
+	source := migrate.HttpFileSystemMigrationSource{FileSystem: http.FS(dir)}
+	return source.FindMigrations()
+}
+
+// stepsTo returns how many migrations must run in dir to reach target.
+// Up applies target included, Down rolls back the migrations applied after target.
+func stepsTo(migrations []*migrate.Migration, records []*migrate.MigrationRecord, dir migrate.MigrationDirection,
+	target string,
+) (int, error) {
+	known := false
+	for _, mig := range migrations {
+		known = known || mig.Id == target
+	}
+
+	if !known {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownMigration, target)
+	}
+
+	applied := make(map[string]bool, len(records))
+	for _, r := range records {
+		applied[r.Id] = true
+	}
+
+	// Last applied migration, in the order sql-migrate applies them
+	current := ""
+	for _, mig := range migrations {
+		if applied[mig.Id] {
+			current = mig.Id
+		}
+	}
+
+	for i, mig := range migrate.ToApply(migrations, current, dir) {
+		if mig.Id != target {
+			continue
+		}
+
+		if dir == migrate.Up {
+			return i + 1, nil
+		}
+
+		return i, nil
+	}
+
+	// Up to an applied migration is a no-op, down to a pending one is not possible
+	if dir == migrate.Up {
+		return 0, nil
+	}
+
+	return 0, fmt.Errorf("migration %s is not applied", target)
+}
+
+func direction(dir migrate.MigrationDirection) string {
+	if dir == migrate.Down {
+		return "rolled back"
+	}
+
+	return "applied"
+}
+
+func dirName(dir migrate.MigrationDirection) string {
+	if dir == migrate.Down {
+		return "down"
+	}
+
+	return "up"
+}
@@ -0,0 +1,196 @@
+package dbkit
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"io/fs"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	migrate "github.com/rubenv/sql-migrate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed testdata/migrations
+var _testMigrations embed.FS
+
+func TestStepsTo(t *testing.T) {
+	migrations := []*migrate.Migration{{Id: "0001_init.sql"}, {Id: "0002_users.sql"}, {Id: "0003_orders.sql"}}
+	applied := func(ids ...string) []*migrate.MigrationRecord {
+		records := make([]*migrate.MigrationRecord, 0, len(ids))
+		for _, id := range ids {
+			records = append(records, &migrate.MigrationRecord{Id: id})
+		}
+
+		return records
+	}
+
+	tests := []struct {
+		name    string
+		records []*migrate.MigrationRecord
+		dir     migrate.MigrationDirection
+		target  string
+		steps   int
+		wantErr string
+	}{
+		{
+			name:   "up to pending target",
+			dir:    migrate.Up,
+			target: "0002_users.sql",
+			steps:  2,
+		},
+		{
+			name:    "up to applied target",
+			records: applied("0001_init.sql", "0002_users.sql"),
+			dir:     migrate.Up,
+			target:  "0001_init.sql",
+			steps:   0,
+		},
+		{
+			name:    "down keeps target applied",
+			records: applied("0001_init.sql", "0002_users.sql", "0003_orders.sql"),
+			dir:     migrate.Down,
+			target:  "0001_init.sql",
+			steps:   2,
+		},
+		{
+			name:    "down to latest applied",
+			records: applied("0001_init.sql", "0002_users.sql"),
+			dir:     migrate.Down,
+			target:  "0002_users.sql",
+			steps:   0,
+		},
+		{
+			name:    "down to pending target",
+			records: applied("0001_init.sql"),
+			dir:     migrate.Down,
+			target:  "0003_orders.sql",
+			wantErr: "not applied",
+		},
+		{
+			name:    "unknown target",
+			dir:     migrate.Up,
+			target:  "0004_missing.sql",
+			wantErr: ErrUnknownMigration.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps, err := stepsTo(migrations, tt.records, tt.dir, tt.target)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.steps, steps)
+		})
+	}
+}
+
+func TestDialectMigrationSource(t *testing.T) {
+	assets, err := fs.Sub(_testMigrations, "testdata")
+	require.NoError(t, err)
+
+	loaded := func(d Dialect) []string {
+		migrations, err := dialectMigrationSource{assets: assets, dialect: d}.FindMigrations()
+		require.NoError(t, err)
+
+		var ids []string
+		for _, m := range migrations {
+			ids = append(ids, m.Id+": "+m.Up[0])
+		}
+
+		return ids
+	}
+
+	assert.Equal(t, []string{
+		"0001_create_a.sql: CREATE TABLE a (id INT);\n",
+		"0002_create_b.sql: CREATE TABLE b (id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY);\n",
+	}, loaded(MySQL))
+
+	// Dialect migrations replace the shared ones of the same name, or are added
+	assert.Equal(t, []string{
+		"0001_create_a.sql: CREATE TABLE a (id INT);\n",
+		"0002_create_b.sql: CREATE TABLE b (id BIGSERIAL PRIMARY KEY);\n",
+		"0003_create_extension.sql: CREATE EXTENSION IF NOT EXISTS pgcrypto;\n",
+	}, loaded(Postgres))
+}
+
+func TestMigrator_PrintPlan(t *testing.T) {
+	var out bytes.Buffer
+	m := &Migrator{dryRun: &out}
+
+	err := m.printPlan(AppMigrations, migrate.Up, []*migrate.PlannedMigration{
+		{
+			Migration: &migrate.Migration{Id: "0002_users.sql"},
+			Queries:   []string{"CREATE TABLE users (id INT);\n"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "-- app up 0002_users.sql\nCREATE TABLE users (id INT);\n", out.String())
+
+	out.Reset()
+	require.NoError(t, m.printPlan(SchemaMigrations, migrate.Down, nil))
+	assert.Equal(t, "-- no schema migrations to apply\n", out.String())
+}
+
+func TestMigrator_EnsureSchema(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	m := &Migrator{dbSchema: "billing", logger: testLogger}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?)")).
+		WithArgs("billing").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE DATABASE IF NOT EXISTS `billing`")).WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, m.ensureSchema(context.Background(), db.DB, MySQL))
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = $1)")).
+		WithArgs("billing").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	require.NoError(t, m.ensureSchema(context.Background(), db.DB, Postgres))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_PlanReadOnly(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	defer db.Close()
+
+	assets, err := fs.Sub(_testMigrations, "testdata")
+	require.NoError(t, err)
+
+	m := &Migrator{dbSchema: "billing", logger: testLogger}
+	s := migrationSet{name: AppMigrations, dialect: MySQL, assets: assets, readOnly: true}
+	tableExists := regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?)")
+
+	// Without migration table every migration is pending and nothing is created
+	mock.ExpectQuery(tableExists).WithArgs("migrations_app_billing").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	planned, err := m.plan(context.Background(), db.DB, s, migrate.Up, 1)
+	require.NoError(t, err)
+	require.Len(t, planned, 1)
+	assert.Equal(t, "0001_create_a.sql", planned[0].Id)
+	assert.Equal(t, []string{"CREATE TABLE a (id INT);\n"}, planned[0].Queries)
+
+	mock.ExpectQuery(tableExists).WithArgs("migrations_app_billing").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	planned, err = m.plan(context.Background(), db.DB, s, migrate.Down, 0)
+	require.NoError(t, err)
+	assert.Empty(t, planned)
+
+	mock.ExpectQuery(tableExists).WithArgs("migrations_app_billing").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	statuses, err := m.status(context.Background(), db.DB, s)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.False(t, statuses[0].Applied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// A missing MySQL database has no migration applied either
+	statuses, err = m.status(context.Background(), nil, s)
+	require.NoError(t, err)
+	assert.Len(t, statuses, 2)
+}
@@ -0,0 +1,107 @@
+package dbkit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAtomic_NestedReleasesSavepoint(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("RELEASE SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := db.Atomic(context.Background(), nil, func(ctx context.Context, outer *sql.Tx) error {
+		return db.Atomic(ctx, nil, func(ctx context.Context, inner *sql.Tx) error {
+			assert.Same(t, outer, inner)
+
+			txn, ok := TxFromContext(ctx)
+			require.True(t, ok)
+			assert.Same(t, inner, txn)
+
+			_, err := db.Executor(ctx).ExecContext(ctx, "INSERT INTO users (id) VALUES (1)")
+			return err
+		})
+	})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAtomic_NestedErrorRollsBackToSavepoint(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	defer db.Close()
+
+	errInner := errors.New("inner failed")
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT sp_2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := db.Atomic(context.Background(), nil, func(ctx context.Context, _ *sql.Tx) error {
+		// A failed nested call can be recovered from by the outer one
+		err := db.Atomic(ctx, nil, func(context.Context, *sql.Tx) error {
+			return errInner
+		})
+		assert.ErrorIs(t, err, errInner)
+
+		// A panic deep down rolls back every savepoint, then the transaction
+		return db.Atomic(ctx, nil, func(ctx context.Context, _ *sql.Tx) error {
+			return db.Atomic(ctx, nil, func(context.Context, *sql.Tx) error {
+				panic("boom")
+			})
+		})
+	})
+	assert.ErrorContains(t, err, "panic occurred, cause: boom")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAtomic_OtherConnBeginsOwnTransaction(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	defer db.Close()
+	other, otherMock := NewMockDB(testLogger)
+	defer other.Close()
+
+	mock.ExpectBegin()
+	otherMock.ExpectBegin()
+	otherMock.ExpectExec("INSERT INTO audits").WillReturnResult(sqlmock.NewResult(1, 1))
+	otherMock.ExpectCommit()
+	mock.ExpectCommit()
+
+	err := db.Atomic(context.Background(), nil, func(ctx context.Context, outer *sql.Tx) error {
+		assert.Same(t, other.DB, other.Executor(ctx))
+
+		return other.Atomic(ctx, nil, func(ctx context.Context, inner *sql.Tx) error {
+			assert.NotSame(t, outer, inner)
+			assert.Same(t, outer, db.Executor(ctx))
+
+			_, err := other.Executor(ctx).ExecContext(ctx, "INSERT INTO audits (id) VALUES (1)")
+			return err
+		})
+	})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.NoError(t, otherMock.ExpectationsWereMet())
+}
+
+func TestExecutor_OutsideTransaction(t *testing.T) {
+	db, _ := NewMockDB(testLogger)
+	defer db.Close()
+
+	_, ok := TxFromContext(context.Background())
+	assert.False(t, ok)
+	assert.Same(t, db.DB, db.Executor(context.Background()))
+}
@@ -0,0 +1,91 @@
+package dbkit
+
+import (
+	"context"
+	"reflect"
+	"slices"
+
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
+	"github.com/wasay-usmani/go-boilerplate/pkg/errorx"
+	"github.com/wasay-usmani/go-boilerplate/pkg/tenantkit"
+)
+
+// TenantColumn holds the tenant owning the rows of tenant scoped tables
+const TenantColumn = "tenant_id"
+
+// TenantScope returns the condition restricting a query to the rows of the tenant of
+// ctx, an errorx.Forbidden when ctx carries no tenant. Soft deleted rows are excluded
+// unless WithDeleted is given.
+func TenantScope(ctx context.Context, opts ...ScopeOption) (qm.QueryMod, error) {
+	tenantID, err := tenantkit.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if newScopeOptions(opts).withDeleted {
+		return qm.Where(TenantColumn+" = ?", tenantID), nil
+	}
+
+	return qm.Where(TenantColumn+" = ? AND "+_notDeleted, tenantID), nil
+}
+
+// ScopeTenant restricts the list to the rows of the tenant of ctx, an errorx.Forbidden
+// when ctx carries no tenant. Lists run through a tenant scoped Repository are already
+// restricted, ScopeTenant is meant for the raw SQL of SQL.
+func (q *ListQuery) ScopeTenant(ctx context.Context) error {
+	tenantID, err := tenantkit.Require(ctx)
+	if err != nil {
+		return err
+	}
+
+	q.Where = slices.Insert(q.Where, 0, TenantColumn+" = ?")
+	q.Args = slices.Insert(q.Args, 0, any(tenantID))
+	return nil
+}
+
+// stampTenant sets the tenant_id column of m to the tenant of ctx.
+func stampTenant(ctx context.Context, m any) error {
+	tenantID, err := tenantkit.Require(ctx)
+	if err != nil {
+		return err
+	}
+
+	field, ok := modelFields(m)[TenantColumn]
+	if !ok {
+		return errorx.New(errorx.Internal, "tenant scoped model has no tenant_id column")
+	}
+
+	setField(field, reflect.ValueOf(tenantID))
+	return nil
+}
+
+// checkTenant ensures m belongs to the tenant of ctx before it is written.
+func checkTenant(ctx context.Context, m any) error {
+	tenantID, err := tenantkit.Require(ctx)
+	if err != nil {
+		return err
+	}
+
+	field, ok := modelFields(m)[TenantColumn]
+	if !ok || stringField(field) != tenantID {
+		return errorx.New(errorx.Forbidden, "record belongs to another tenant")
+	}
+
+	return nil
+}
+
+// stringField returns the value of a string, *string or nullable string field, "" when unset.
+func stringField(field reflect.Value) string {
+	switch {
+	case field.Kind() == reflect.String:
+		return field.String()
+	case field.Kind() == reflect.Pointer && !field.IsNil() && field.Elem().Kind() == reflect.String:
+		return field.Elem().String()
+	case field.Kind() == reflect.Struct:
+		if inner := field.FieldByName("String"); inner.IsValid() && inner.Kind() == reflect.String {
+			return inner.String()
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,186 @@
+package dbkit
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasay-usmani/go-boilerplate/pkg/errorx"
+	"github.com/wasay-usmani/go-boilerplate/pkg/tenantkit"
+)
+
+// tenantThing mimics a sqlboiler model of a tenant scoped table.
+type tenantThing struct {
+	ID       int    `boil:"id" json:"id"`
+	TenantID string `boil:"tenant_id" json:"tenant_id"`
+}
+
+func (t *tenantThing) Insert(ctx context.Context, exec boil.ContextExecutor, _ boil.Columns) error {
+	_, err := exec.ExecContext(ctx, "INSERT INTO `things` (`tenant_id`) VALUES (?)", t.TenantID)
+	return err
+}
+
+func (t *tenantThing) Update(ctx context.Context, exec boil.ContextExecutor, _ boil.Columns) (int64, error) {
+	res, err := exec.ExecContext(ctx, "UPDATE `things` SET `tenant_id` = ? WHERE `id` = ?", t.TenantID, t.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (t *tenantThing) Delete(ctx context.Context, exec boil.ContextExecutor) (int64, error) {
+	res, err := exec.ExecContext(ctx, "DELETE FROM `things` WHERE `id` = ?", t.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// tenantThingQuery only implements Exists, the rows of tenantThing are never read.
+type tenantThingQuery struct {
+	thingQuery
+}
+
+func tenantThings(mods ...qm.QueryMod) tenantThingQuery {
+	return tenantThingQuery{things(mods...)}
+}
+
+func (q tenantThingQuery) One(context.Context, boil.ContextExecutor) (*tenantThing, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (q tenantThingQuery) All(context.Context, boil.ContextExecutor) ([]*tenantThing, error) {
+	return nil, nil
+}
+
+func TestRepository_TenantScopedReads(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	defer db.Close()
+
+	repo := NewRepository[*thing, thingSlice](db, things, "id", WithTenantScope())
+	ctx := tenantkit.WithTenant(context.Background(), "acme")
+
+	mock.ExpectQuery("SELECT \\* FROM `things` WHERE \\(tenant_id = \\?\\) AND \\(id = \\?\\) LIMIT 1").WithArgs("acme", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a"))
+	_, err := repo.Get(ctx, 1)
+	require.NoError(t, err)
+
+	_, err = repo.Get(context.Background(), 1)
+	assert.True(t, errorx.Is(err, errorx.Forbidden))
+	_, err = repo.List(context.Background())
+	assert.True(t, errorx.Is(err, errorx.Forbidden))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_TenantScopedWrites(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	defer db.Close()
+
+	repo := NewRepository[*tenantThing, []*tenantThing](db, tenantThings, "id", WithTenantScope())
+	ctx := tenantkit.WithTenant(context.Background(), "acme")
+
+	mock.ExpectExec("INSERT INTO `things`").WithArgs("acme").WillReturnResult(sqlmock.NewResult(1, 1))
+	th := &tenantThing{ID: 1, TenantID: "other"}
+	require.NoError(t, repo.Create(ctx, th))
+	assert.Equal(t, "acme", th.TenantID)
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM `things` WHERE \\(tenant_id = \\?\\) AND \\(id = \\?\\)").WithArgs("acme", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"n"}).AddRow(1))
+	mock.ExpectExec("UPDATE `things`").WithArgs("acme", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, repo.Update(ctx, th))
+
+	// The row of another tenant is not written, even when the model claims the tenant
+	mismatched := &tenantThing{ID: 2, TenantID: "acme"}
+	for range 2 {
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM `things` WHERE \\(tenant_id = \\?\\) AND \\(id = \\?\\)").WithArgs("acme", 2).
+			WillReturnRows(sqlmock.NewRows([]string{"n"}).AddRow(0))
+	}
+
+	err := repo.Update(ctx, mismatched)
+	assert.True(t, errorx.Is(err, errorx.NotFound))
+	err = repo.Delete(ctx, mismatched)
+	assert.True(t, errorx.Is(err, errorx.NotFound))
+
+	err = repo.Update(ctx, &tenantThing{ID: 2, TenantID: "other"})
+	assert.True(t, errorx.Is(err, errorx.Forbidden))
+	err = repo.Create(context.Background(), &tenantThing{})
+	assert.True(t, errorx.Is(err, errorx.Forbidden))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_TenantCheckOnPrimary(t *testing.T) {
+	primary, primaryMock := newTestConn(t)
+	replica, replicaMock := newTestConn(t)
+	router := NewRouter(primary, []*SQLConn{replica}, testLogger, WithHealthCheckInterval(time.Hour))
+	defer router.Close()
+
+	repo := NewRepository[*tenantThing, []*tenantThing](router, tenantThings, "id", WithTenantScope())
+	ctx := tenantkit.WithTenant(context.Background(), "acme")
+
+	// The row was just created, a lagging replica would not find it
+	primaryMock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM `things`").WithArgs("acme", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"n"}).AddRow(1))
+	primaryMock.ExpectExec("UPDATE `things`").WithArgs("acme", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, repo.Update(ctx, &tenantThing{ID: 1, TenantID: "acme"}))
+
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+}
+
+func TestVersioned_TenantScoped(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	defer db.Close()
+
+	v := Versioned{Table: "things", KeyColumn: "id", TenantScoped: true}
+	ctx := tenantkit.WithTenant(context.Background(), "acme")
+	mock.ExpectExec(`UPDATE things SET name = \?, version = version \+ 1 WHERE id = \? AND tenant_id = \? AND deleted_at IS NULL AND version = \?`).
+		WithArgs("b", 7, "acme", int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT version FROM things WHERE id = \? AND tenant_id = \? AND deleted_at IS NULL`).WithArgs(7, "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"version"}))
+
+	_, err := v.Update(ctx, db, 7, 3, map[string]any{"name": "b"})
+	assert.True(t, errorx.Is(err, errorx.NotFound))
+
+	_, err = v.Update(context.Background(), db, 7, 3, map[string]any{"name": "b"})
+	assert.True(t, errorx.Is(err, errorx.Forbidden))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTenantScope(t *testing.T) {
+	ctx := tenantkit.WithTenant(context.Background(), "acme")
+
+	scope, err := TenantScope(ctx)
+	require.NoError(t, err)
+	query, args := buildQuery(t, []qm.QueryMod{scope})
+	assert.Equal(t, "SELECT * FROM `users` WHERE (tenant_id = ? AND deleted_at IS NULL);", query)
+	assert.Equal(t, []any{"acme"}, args)
+
+	scope, err = TenantScope(ctx, WithDeleted())
+	require.NoError(t, err)
+	query, _ = buildQuery(t, []qm.QueryMod{scope})
+	assert.Equal(t, "SELECT * FROM `users` WHERE (tenant_id = ?);", query)
+
+	_, err = TenantScope(context.Background())
+	assert.True(t, errorx.Is(err, errorx.Forbidden))
+}
+
+func TestListQuery_ScopeTenant(t *testing.T) {
+	q := &ListQuery{Where: []string{"status = ?"}, Args: []any{"active"}, Limit: 10}
+	require.NoError(t, q.ScopeTenant(tenantkit.WithTenant(context.Background(), "acme")))
+
+	query, args := q.SQL(Postgres)
+	assert.Equal(t, " WHERE deleted_at IS NULL AND tenant_id = $1 AND status = $2 LIMIT 10", query)
+	assert.Equal(t, []any{"acme", "active"}, args)
+
+	err := (&ListQuery{}).ScopeTenant(context.Background())
+	assert.True(t, errorx.Is(err, errorx.Forbidden))
+}
@@ -0,0 +1,5 @@
+-- +migrate Up
+CREATE TABLE a (id INT);
+
+-- +migrate Down
+DROP TABLE a;
@@ -0,0 +1,5 @@
+-- +migrate Up
+CREATE TABLE b (id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY);
+
+-- +migrate Down
+DROP TABLE b;
@@ -0,0 +1,5 @@
+-- +migrate Up
+CREATE TABLE b (id BIGSERIAL PRIMARY KEY);
+
+-- +migrate Down
+DROP TABLE b;
@@ -0,0 +1,5 @@
+-- +migrate Up
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+-- +migrate Down
+DROP EXTENSION IF EXISTS pgcrypto;
@@ -0,0 +1,52 @@
+package dbkit
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const _tracerName = "github.com/wasay-usmani/go-boilerplate/pkg/dbkit"
+
+// TraceObserver attaches every query to the trace of its context as an OpenTelemetry
+// client span, named after the operation and table, e.g. "select users". Queries
+// run outside of a trace are not recorded.
+type TraceObserver struct {
+	tracer trace.Tracer
+}
+
+// NewTraceObserver returns a QueryObserver recording spans with tp, usually
+// otel.GetTracerProvider() once the service has set up its exporter.
+func NewTraceObserver(tp trace.TracerProvider) *TraceObserver {
+	return &TraceObserver{tracer: tp.Tracer(_tracerName)}
+}
+
+func (o *TraceObserver) ObserveQuery(ctx context.Context, e QueryEvent) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+
+	name := e.Operation
+	if e.Table != "" {
+		name += " " + e.Table
+	}
+
+	_, span := o.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(e.Start),
+		trace.WithAttributes(
+			attribute.String("db.operation.name", e.Operation),
+			attribute.String("db.collection.name", e.Table),
+			attribute.String("db.query.text", e.Query),
+		),
+	)
+
+	if e.Err != nil {
+		span.RecordError(e.Err)
+		span.SetStatus(codes.Error, e.Err.Error())
+	}
+
+	span.End(trace.WithTimestamp(e.Start.Add(e.Duration)))
+}
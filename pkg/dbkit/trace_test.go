@@ -0,0 +1,83 @@
+package dbkit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// spanRecorder is a Tracer keeping the spans it starts.
+type spanRecorder struct {
+	noop.Tracer
+	spans []*recordedSpan
+}
+
+type recorderProvider struct {
+	noop.TracerProvider
+	tracer *spanRecorder
+}
+
+type recordedSpan struct {
+	noop.Span
+	name       string
+	kind       trace.SpanKind
+	attributes []attribute.KeyValue
+	start, end time.Time
+	status     codes.Code
+}
+
+func (p recorderProvider) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return p.tracer
+}
+
+func (r *spanRecorder) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+	s := &recordedSpan{name: name, kind: cfg.SpanKind(), attributes: cfg.Attributes(), start: cfg.Timestamp()}
+	r.spans = append(r.spans, s)
+	return ctx, s
+}
+
+func (s *recordedSpan) End(opts ...trace.SpanEndOption) {
+	cfg := trace.NewSpanEndConfig(opts...)
+	s.end = cfg.Timestamp()
+}
+
+func (s *recordedSpan) SetStatus(code codes.Code, _ string) {
+	s.status = code
+}
+
+func TestTraceObserver(t *testing.T) {
+	rec := &spanRecorder{}
+	o := NewTraceObserver(recorderProvider{tracer: rec})
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	e := QueryEvent{Operation: "select", Table: "users", Query: "SELECT id FROM users WHERE id = ?", Start: start, Duration: time.Second}
+
+	// Queries outside of a trace are not recorded
+	o.ObserveQuery(context.Background(), e)
+	assert.Empty(t, rec.spans)
+
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1}, SpanID: trace.SpanID{1},
+	}))
+	o.ObserveQuery(ctx, e)
+	e.Err = errors.New("boom")
+	o.ObserveQuery(ctx, e)
+
+	require.Len(t, rec.spans, 2)
+	span := rec.spans[0]
+	assert.Equal(t, "select users", span.name)
+	assert.Equal(t, trace.SpanKindClient, span.kind)
+	assert.Equal(t, start, span.start)
+	assert.Equal(t, start.Add(time.Second), span.end)
+	assert.Contains(t, span.attributes, attribute.String("db.query.text", e.Query))
+	assert.Equal(t, codes.Unset, span.status)
+	assert.Equal(t, codes.Error, rec.spans[1].status)
+}
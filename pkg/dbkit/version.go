@@ -0,0 +1,193 @@
+package dbkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/wasay-usmani/go-boilerplate/pkg/errorx"
+	"github.com/wasay-usmani/go-boilerplate/pkg/tenantkit"
+	"github.com/wasay-usmani/go-boilerplate/pkg/timex"
+)
+
+const (
+	// VersionField is the errorx field of version conflicts holding the current version of the row
+	VersionField = "current_version"
+
+	_defaultVersionColumn = "version"
+)
+
+// ErrStaleVersion is wrapped by the errorx.Conflict returned when a row was modified
+// since the version the update is based on was read.
+var ErrStaleVersion = errors.New("stale row version")
+
+// Versioned updates the rows of Table under optimistic concurrency control: every
+// update is conditioned on the version read by the caller and increments it, so a
+// concurrent edit is detected instead of silently overwritten. The version column
+// is an integer, e.g. version BIGINT NOT NULL DEFAULT 1. Timestamp columns such as
+// updated_at are not supported as versions: their precision differs by dialect and
+// column type, and two updates within the same tick would share a version.
+type Versioned struct {
+	Table     string
+	KeyColumn string
+	// VersionColumn defaults to version
+	VersionColumn string
+	// Dialect sets the placeholders, MySQL by default
+	Dialect Dialect
+	// TenantScoped restricts updates to the rows of the tenant of the context, see tenantkit
+	TenantScoped bool
+	// Audit stamps updated_at with timex.Now and updated_by with the actor of the context,
+	// like AuditUpdateHook, unless set already sets them
+	Audit bool
+}
+
+// Update sets the set columns of the row with key and increments its version, when
+// the version is still expected. It returns the new version. A row modified in the
+// meantime returns an errorx.Conflict with the current version in Fields, see
+// CurrentVersion, a missing or soft deleted row an errorx.NotFound, unless WithDeleted
+// is given. Table and columns come from code, never from user input.
+func (v Versioned) Update(
+	ctx context.Context, exec boil.ContextExecutor, key any, expected int64, set map[string]any, opts ...ScopeOption,
+) (int64, error) {
+	where, whereArgs, err := v.where(ctx, key, newScopeOptions(opts))
+	if err != nil {
+		return 0, err
+	}
+
+	if v.Audit {
+		set = auditSet(ctx, set)
+	}
+
+	versionCol := v.versionColumn()
+	columns := make([]string, 0, len(set))
+	for col := range set {
+		columns = append(columns, col)
+	}
+
+	slices.Sort(columns)
+	assignments := make([]string, 0, len(columns)+1)
+	args := make([]any, 0, len(columns)+len(whereArgs)+1)
+	for _, col := range columns {
+		assignments = append(assignments, col+" = ?")
+		args = append(args, set[col])
+	}
+
+	assignments = append(assignments, fmt.Sprintf("%s = %s + 1", versionCol, versionCol))
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s AND %s = ?", v.Table, strings.Join(assignments, ", "), where, versionCol)
+	args = append(append(args, whereArgs...), expected)
+	res, err := exec.ExecContext(ctx, v.rebind(query), args...)
+	if err != nil {
+		return 0, MapError(err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if n == 0 {
+		return 0, v.conflict(ctx, exec, where, whereArgs, expected)
+	}
+
+	return expected + 1, nil
+}
+
+// auditSet returns a copy of set completed with the updated_at and updated_by columns,
+// see stampAudit.
+func auditSet(ctx context.Context, set map[string]any) map[string]any {
+	set = maps.Clone(set)
+	if _, ok := set[UpdatedAtColumn]; !ok && !boil.TimestampsAreSkipped(ctx) {
+		set[UpdatedAtColumn] = timex.Now()
+	}
+
+	if actor, ok := ActorFromContext(ctx); ok {
+		if _, ok := set[UpdatedByColumn]; !ok {
+			set[UpdatedByColumn] = actor
+		}
+	}
+
+	return set
+}
+
+// conflict explains an update that matched no row: the row is gone or its version moved on.
+// The version is read on the primary, a replica may lag behind the row.
+func (v Versioned) conflict(ctx context.Context, exec boil.ContextExecutor, where string, whereArgs []any, expected int64) error {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s", v.versionColumn(), v.Table, where)
+
+	var current int64
+	if err := exec.QueryRowContext(WithPrimary(ctx), v.rebind(query), whereArgs...).Scan(&current); err != nil {
+		return MapError(err)
+	}
+
+	return errorx.New(errorx.Conflict, "record was modified concurrently",
+		errorx.WithFields(map[string]any{VersionField: current, "expected_version": expected}),
+		errorx.WithCause(ErrStaleVersion),
+	)
+}
+
+// where returns the condition selecting the row with key, within the tenant of ctx when
+// tenant scoped and not soft deleted unless o includes deleted rows.
+func (v Versioned) where(ctx context.Context, key any, o scopeOptions) (string, []any, error) {
+	where, args := v.KeyColumn+" = ?", []any{key}
+	if v.TenantScoped {
+		tenantID, err := tenantkit.Require(ctx)
+		if err != nil {
+			return "", nil, err
+		}
+
+		where += fmt.Sprintf(" AND %s = ?", TenantColumn)
+		args = append(args, tenantID)
+	}
+
+	if !o.withDeleted {
+		where += " AND " + _notDeleted
+	}
+
+	return where, args, nil
+}
+
+func (v Versioned) versionColumn() string {
+	if v.VersionColumn != "" {
+		return v.VersionColumn
+	}
+
+	return _defaultVersionColumn
+}
+
+func (v Versioned) rebind(query string) string {
+	if v.Dialect == Postgres {
+		return numberPlaceholders(query)
+	}
+
+	return query
+}
+
+// CurrentVersion returns the current row version held by a version conflict returned by Versioned.Update.
+func CurrentVersion(err error) (int64, bool) {
+	var e *errorx.Error
+	if !errors.Is(err, ErrStaleVersion) || !errors.As(err, &e) {
+		return 0, false
+	}
+
+	version, ok := e.Fields[VersionField].(int64)
+	return version, ok
+}
+
+// UpdateVersioned updates the row with the primary key id under optimistic concurrency
+// control, see Versioned.Update. v.KeyColumn defaults to the primary key of the repository,
+// and the update is tenant scoped when the repository is.
+func (r *Repository[M, S, Q]) UpdateVersioned(
+	ctx context.Context, v Versioned, id any, expected int64, set map[string]any, opts ...ScopeOption,
+) (int64, error) {
+	if v.KeyColumn == "" {
+		v.KeyColumn = r.pk
+	}
+
+	v.TenantScoped = v.TenantScoped || r.tenantScoped
+
+	return v.Update(ctx, r.Executor(ctx), id, expected, set, opts...)
+}
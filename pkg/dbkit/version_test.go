@@ -0,0 +1,96 @@
+package dbkit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasay-usmani/go-boilerplate/pkg/errorx"
+)
+
+func TestVersioned_Update(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	defer db.Close()
+
+	v := Versioned{Table: "things", KeyColumn: "id"}
+	mock.ExpectExec(`UPDATE things SET name = \?, status = \?, version = version \+ 1 WHERE id = \? AND deleted_at IS NULL AND version = \?`).
+		WithArgs("b", "active", 7, int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	version, err := v.Update(context.Background(), db, 7, 3, map[string]any{"status": "active", "name": "b"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), version)
+
+	// Soft deleted rows are only updated on request
+	mock.ExpectExec(`UPDATE things SET name = \?, version = version \+ 1 WHERE id = \? AND version = \?`).
+		WithArgs("c", 7, int64(4)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err = v.Update(context.Background(), db, 7, 4, map[string]any{"name": "c"}, WithDeleted())
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestVersioned_UpdateAudit(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	defer db.Close()
+
+	v := Versioned{Table: "things", KeyColumn: "id", Audit: true}
+	set := map[string]any{"name": "b"}
+	mock.ExpectExec(`UPDATE things SET name = \?, updated_at = \?, updated_by = \?, version = version \+ 1 WHERE id = \? AND deleted_at IS NULL AND version = \?`).
+		WithArgs("b", sqlmock.AnyArg(), "user-1", 7, int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err := v.Update(WithActor(context.Background(), "user-1"), db, 7, 3, set)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"name": "b"}, set)
+
+	// Without actor only updated_at is stamped
+	mock.ExpectExec(`UPDATE things SET name = \?, updated_at = \?, version = version \+ 1 WHERE id = \? AND deleted_at IS NULL AND version = \?`).
+		WithArgs("c", sqlmock.AnyArg(), 7, int64(4)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err = v.Update(context.Background(), db, 7, 4, map[string]any{"name": "c"})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestVersioned_UpdateConflict(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	defer db.Close()
+
+	v := Versioned{Table: "things", KeyColumn: "id", VersionColumn: "revision", Dialect: Postgres}
+	mock.ExpectExec(`UPDATE things SET name = \$1, revision = revision \+ 1 WHERE id = \$2 AND deleted_at IS NULL AND revision = \$3`).
+		WithArgs("b", 7, int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT revision FROM things WHERE id = \$1 AND deleted_at IS NULL`).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(5))
+
+	_, err := v.Update(context.Background(), db, 7, 3, map[string]any{"name": "b"})
+	assert.True(t, errorx.Is(err, errorx.Conflict))
+	require.ErrorIs(t, err, ErrStaleVersion)
+	current, ok := CurrentVersion(err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(5), current)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_UpdateVersionedMissing(t *testing.T) {
+	db, mock := NewMockDB(testLogger)
+	defer db.Close()
+
+	repo := NewRepository[*thing, thingSlice](db, things, "id")
+	mock.ExpectExec(`UPDATE things SET name = \?, version = version \+ 1 WHERE id = \? AND deleted_at IS NULL AND version = \?`).
+		WithArgs("b", 9, int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT version FROM things WHERE id = \? AND deleted_at IS NULL`).WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}))
+
+	_, err := repo.UpdateVersioned(context.Background(), Versioned{Table: "things"}, 9, 1, map[string]any{"name": "b"})
+	assert.True(t, errorx.Is(err, errorx.NotFound))
+	_, ok := CurrentVersion(err)
+	assert.False(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
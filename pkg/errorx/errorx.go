@@ -1,6 +1,7 @@
 package errorx
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -33,6 +34,9 @@ type Error struct {
 	Message      string         `json:"message"`
 	InternalCode string         `json:"internal_code,omitempty"`
 	Fields       map[string]any `json:"fields,omitempty"`
+
+	// cause is the underlying error, returned by Unwrap
+	cause error
 }
 
 // NewValidation creates a validation error with fields
@@ -67,6 +71,13 @@ func WithFields(fields map[string]any) Option {
 	}
 }
 
+// WithCause sets the underlying error, so errors.Is and errors.As still match it
+func WithCause(err error) Option {
+	return func(e *Error) {
+		e.cause = err
+	}
+}
+
 // New creates a new Error with options
 func New(code ErrorCode, message string, opts ...Option) *Error {
 	err := &Error{
@@ -81,10 +92,15 @@ func New(code ErrorCode, message string, opts ...Option) *Error {
 	return err
 }
 
-// Is checks if the error matches the given ErrorCode
+// Unwrap returns the underlying error set with WithCause
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is checks if the error, or an error it wraps, matches the given ErrorCode
 func Is(err error, code ErrorCode) bool {
-	e, ok := err.(*Error)
-	return ok && e.Code == code
+	var e *Error
+	return errors.As(err, &e) && e.Code == code
 }
 
 // ToHTTPStatus converts an internal error code to an HTTP status code
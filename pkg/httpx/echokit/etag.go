@@ -0,0 +1,31 @@
+package echokit
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/wasay-usmani/go-boilerplate/pkg/httpx"
+)
+
+// IfMatchVersion returns the row version of the If-Match request header, to pass as
+// the expected version of an optimistic concurrency update. ok is false when the header is
+// absent or "*". A header that is not a single tag set by SetETag is a 400.
+func IfMatchVersion(c echo.Context) (version int64, ok bool, err error) {
+	header := c.Request().Header.Get(httpx.IfMatchHeader)
+	if header == "" || header == "*" {
+		return 0, false, nil
+	}
+
+	version, err = httpx.ParseETag(header)
+	if err != nil {
+		return 0, false, echo.NewHTTPError(http.StatusBadRequest, "invalid If-Match header").SetInternal(err)
+	}
+
+	return version, true, nil
+}
+
+// SetETag sets the ETag response header to the row version.
+func SetETag(c echo.Context, version int64) {
+	c.Response().Header().Set(httpx.ETagHeader, httpx.ETag(version))
+}
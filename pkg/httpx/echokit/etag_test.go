@@ -0,0 +1,42 @@
+package echokit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wasay-usmani/go-boilerplate/pkg/httpx"
+)
+
+func newContext(ifMatch string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(http.MethodPut, "/users/1", nil)
+	if ifMatch != "" {
+		req.Header.Set(httpx.IfMatchHeader, ifMatch)
+	}
+
+	rec := httptest.NewRecorder()
+	return echo.New().NewContext(req, rec), rec
+}
+
+func TestIfMatchVersion(t *testing.T) {
+	c, _ := newContext(httpx.ETag(3))
+	version, ok, err := IfMatchVersion(c)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), version)
+
+	c, _ = newContext("")
+	_, ok, err = IfMatchVersion(c)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	c, _ = newContext(`W/"3"`)
+	_, _, err = IfMatchVersion(c)
+	var he *echo.HTTPError
+	require.ErrorAs(t, err, &he)
+	assert.Equal(t, http.StatusBadRequest, he.Code)
+}
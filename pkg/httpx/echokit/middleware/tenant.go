@@ -0,0 +1,102 @@
+package echomiddlewarekit
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/wasay-usmani/go-boilerplate/pkg/httpx"
+	"github.com/wasay-usmani/go-boilerplate/pkg/tenantkit"
+)
+
+// TenantConfig defines the config of the Tenant middleware.
+type TenantConfig struct {
+	// Skipper skips the middleware, by default for the health check and metrics endpoints
+	Skipper middleware.Skipper
+	// Header carries the tenant ID, tenantkit.Header by default
+	Header string
+	// ClaimsKey is the echo context key the authentication middleware stores the verified
+	// JWT claims under, e.g. "user". When set the tenant is read from the claims only
+	ClaimsKey string
+	// Claim holds the tenant ID in the claims, tenantkit.Claim by default
+	Claim string
+}
+
+// Tenant returns middleware carrying the tenant of the request in its context, see
+// tenantkit. The tenant is read from the verified JWT claims when cfg.ClaimsKey is set,
+// so register it after the authentication middleware, and from the header otherwise,
+// which must then be set by a trusted gateway. Requests without a tenant are rejected
+// with a 403.
+func Tenant(cfg TenantConfig) echo.MiddlewareFunc {
+	if cfg.Skipper == nil {
+		cfg.Skipper = func(c echo.Context) bool {
+			path := c.Request().URL.Path
+			return strings.HasSuffix(path, httpx.HealthCheckPath) || path == httpx.MetricsPath
+		}
+	}
+
+	if cfg.Header == "" {
+		cfg.Header = tenantkit.Header
+	}
+
+	if cfg.Claim == "" {
+		cfg.Claim = tenantkit.Claim
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if cfg.Skipper(c) {
+				return next(c)
+			}
+
+			tenantID := strings.TrimSpace(c.Request().Header.Get(cfg.Header))
+			if cfg.ClaimsKey != "" {
+				tenantID = claim(c.Get(cfg.ClaimsKey), cfg.Claim)
+			}
+
+			if tenantID == "" {
+				return echo.NewHTTPError(http.StatusForbidden, "tenant is required")
+			}
+
+			req := c.Request()
+			c.SetRequest(req.WithContext(tenantkit.WithTenant(req.Context(), tenantID)))
+			return next(c)
+		}
+	}
+}
+
+// claim returns the string claim name of claims, a map such as jwt.MapClaims or a
+// token such as *jwt.Token holding one in its Claims field.
+func claim(claims any, name string) string {
+	v := reflect.ValueOf(claims)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return ""
+		}
+
+		value := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		if !value.IsValid() {
+			return ""
+		}
+
+		s, _ := value.Interface().(string)
+		return s
+	case reflect.Struct:
+		if field := v.FieldByName("Claims"); field.IsValid() && field.CanInterface() {
+			return claim(field.Interface(), name)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,70 @@
+package echomiddlewarekit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasay-usmani/go-boilerplate/pkg/httpx"
+	"github.com/wasay-usmani/go-boilerplate/pkg/tenantkit"
+)
+
+type mapClaims map[string]any
+
+type token struct {
+	Claims mapClaims
+}
+
+func serveTenant(t *testing.T, cfg TenantConfig, path string, setup func(*http.Request, echo.Context)) (string, error) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	c := echo.New().NewContext(req, httptest.NewRecorder())
+	if setup != nil {
+		setup(req, c)
+	}
+
+	var tenantID string
+	err := Tenant(cfg)(func(c echo.Context) error {
+		tenantID, _ = tenantkit.FromContext(c.Request().Context())
+		return nil
+	})(c)
+
+	return tenantID, err
+}
+
+func TestTenant_Header(t *testing.T) {
+	tenantID, err := serveTenant(t, TenantConfig{}, "/api/v1/users", func(req *http.Request, _ echo.Context) {
+		req.Header.Set(tenantkit.Header, "acme")
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "acme", tenantID)
+
+	_, err = serveTenant(t, TenantConfig{}, "/api/v1/users", nil)
+	var he *echo.HTTPError
+	require.ErrorAs(t, err, &he)
+	assert.Equal(t, http.StatusForbidden, he.Code)
+
+	_, err = serveTenant(t, TenantConfig{}, "/api/v1"+httpx.HealthCheckPath, nil)
+	assert.NoError(t, err)
+}
+
+func TestTenant_Claims(t *testing.T) {
+	cfg := TenantConfig{ClaimsKey: "user"}
+	tenantID, err := serveTenant(t, cfg, "/api/v1/users", func(req *http.Request, c echo.Context) {
+		req.Header.Set(tenantkit.Header, "spoofed")
+		c.Set("user", &token{Claims: mapClaims{tenantkit.Claim: "acme"}})
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "acme", tenantID)
+
+	_, err = serveTenant(t, cfg, "/api/v1/users", func(req *http.Request, _ echo.Context) {
+		req.Header.Set(tenantkit.Header, "spoofed")
+	})
+	var he *echo.HTTPError
+	require.ErrorAs(t, err, &he)
+	assert.Equal(t, http.StatusForbidden, he.Code)
+}
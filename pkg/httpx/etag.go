@@ -0,0 +1,31 @@
+package httpx
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidETag is returned by ParseETag for tags not produced by ETag.
+var ErrInvalidETag = errors.New("invalid entity tag")
+
+// ETag returns the strong entity tag of a row version, e.g. "3".
+func ETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// ParseETag returns the row version of an entity tag produced by ETag. Weak tags
+// are rejected, they cannot be used to condition updates.
+func ParseETag(tag string) (int64, error) {
+	tag = strings.TrimSpace(tag)
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, ErrInvalidETag
+	}
+
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil {
+		return 0, ErrInvalidETag
+	}
+
+	return version, nil
+}
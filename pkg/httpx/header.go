@@ -5,4 +5,6 @@ const (
 	RequestIDHeader     = "X-Request-Id"
 	AuthorizationHeader = "Authorization"
 	BearerHeader        = "bearer"
+	ETagHeader          = "ETag"
+	IfMatchHeader       = "If-Match"
 )
@@ -2,6 +2,7 @@ package logkit
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"os"
 	"time"
@@ -68,6 +69,17 @@ func WithOutput(w io.Writer) LogOpt {
 	}
 }
 
+// SetLevel changes the global log level of every logger at runtime.
+func SetLevel(logLevel LogLevel) error {
+	level, ok := logLevelMap[logLevel]
+	if !ok {
+		return fmt.Errorf("unknown log level %q", logLevel)
+	}
+
+	zerolog.SetGlobalLevel(level)
+	return nil
+}
+
 func (z *zeroLogger) Base() any {
 	return z.l
 }
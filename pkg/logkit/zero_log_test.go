@@ -7,7 +7,6 @@ import (
 	"testing"
 
 	"github.com/pkg/errors"
-
 	"github.com/rs/zerolog"
 )
 
@@ -115,3 +114,27 @@ func TestLogger_WithContext(t *testing.T) {
 		t.Errorf("Expected log message to be present, got: %s", out)
 	}
 }
+
+func TestSetLevel(t *testing.T) {
+	// The level is global, restore it for the tests that follow
+	level := zerolog.GlobalLevel()
+	t.Cleanup(func() { zerolog.SetGlobalLevel(level) })
+
+	buf := &bytes.Buffer{}
+	logger := NewLogger(Info, "gotils-test", WithOutput(buf))
+
+	logger.Debug("hidden debug")
+	if err := SetLevel(Debug); err != nil {
+		t.Fatalf("Expected no error setting debug level, got: %v", err)
+	}
+
+	logger.Debug("visible debug")
+	out := buf.String()
+	if strings.Contains(out, "hidden debug") || !strings.Contains(out, "visible debug") {
+		t.Errorf("Expected only the debug log after SetLevel, got: %s", out)
+	}
+
+	if err := SetLevel("verbose"); err == nil {
+		t.Error("Expected error for unknown log level")
+	}
+}
@@ -0,0 +1,163 @@
+package runkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/wasay-usmani/go-boilerplate/pkg/logkit"
+)
+
+// Server is a blocking server that can be drained gracefully.
+// Run blocks until the server stops and Shutdown drains in-flight work before the context expires.
+type Server interface {
+	Run() error
+	Shutdown(ctx context.Context) error
+}
+
+// Manager starts a set of servers and background workers, waits for a shutdown
+// signal or the first fatal error, then drains all of them within a deadline.
+type Manager struct {
+	logger          logkit.Logger
+	shutdownTimeout time.Duration
+	components      []component
+}
+
+type component struct {
+	name     string
+	run      func(ctx context.Context) error
+	shutdown func(ctx context.Context) error
+}
+
+type httpServer struct {
+	server *http.Server
+}
+
+// NewManager returns a Manager that gives its components shutdownTimeout to drain.
+func NewManager(l logkit.Logger, shutdownTimeout time.Duration) *Manager {
+	return &Manager{
+		logger:          l,
+		shutdownTimeout: shutdownTimeout,
+	}
+}
+
+// AddServer registers a server. It is started by Run and drained through Shutdown.
+func (m *Manager) AddServer(name string, s Server) {
+	m.components = append(m.components, component{
+		name: name,
+		run: func(context.Context) error {
+			return s.Run()
+		},
+		shutdown: s.Shutdown,
+	})
+}
+
+// AddWorker registers a background worker. The context passed to fn is cancelled
+// when shutdown starts and fn is expected to return promptly afterwards.
+func (m *Manager) AddWorker(name string, fn func(ctx context.Context) error) {
+	m.components = append(m.components, component{
+		name: name,
+		run:  fn,
+	})
+}
+
+// Run starts every registered component and blocks until ctx is done or a component fails.
+// It then drains all components within the shutdown timeout and returns the first fatal
+// error joined with any shutdown errors. Run returns nil on a clean shutdown.
+func (m *Manager) Run(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errCh := make(chan error, len(m.components))
+	var running sync.WaitGroup
+	for _, c := range m.components {
+		running.Add(1)
+		go func(c component) {
+			defer running.Done()
+			m.logger.Info("starting component", "component", c.name)
+			if err := c.run(runCtx); err != nil && !errors.Is(err, context.Canceled) {
+				errCh <- fmt.Errorf("%s: %w", c.name, err)
+			}
+		}(c)
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		m.logger.Info("shutdown signal received")
+	case runErr = <-errCh:
+		m.logger.Error("component failed, shutting down", runErr)
+	}
+
+	cancel()
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), m.shutdownTimeout)
+	defer shutdownCancel()
+
+	errs := []error{runErr}
+	errs = append(errs, m.shutdown(shutdownCtx)...)
+
+	stopped := make(chan struct{})
+	go func() {
+		running.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		m.logger.Info("all components stopped")
+	case <-shutdownCtx.Done():
+		errs = append(errs, fmt.Errorf("shutdown deadline exceeded: %w", shutdownCtx.Err()))
+	}
+
+	return errors.Join(errs...)
+}
+
+// shutdown drains all servers concurrently and collects their errors.
+func (m *Manager) shutdown(ctx context.Context) []error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+
+	for _, c := range m.components {
+		if c.shutdown == nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func(c component) {
+			defer wg.Done()
+			m.logger.Info("shutting down component", "component", c.name)
+			if err := c.shutdown(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s shutdown: %w", c.name, err))
+				mu.Unlock()
+			}
+		}(c)
+	}
+
+	wg.Wait()
+	return errs
+}
+
+// HTTPServer adapts an *http.Server to the Server interface.
+// http.ErrServerClosed is treated as a clean stop.
+func HTTPServer(s *http.Server) Server {
+	return &httpServer{server: s}
+}
+
+func (h *httpServer) Run() error {
+	if err := h.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+func (h *httpServer) Shutdown(ctx context.Context) error {
+	return h.server.Shutdown(ctx)
+}
@@ -0,0 +1,90 @@
+package runkit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wasay-usmani/go-boilerplate/pkg/logkit"
+)
+
+var testLogger = logkit.NewLogger(logkit.Debug, "runkit-test", logkit.WithOutput(io.Discard))
+
+type fakeServer struct {
+	stop     chan struct{}
+	runErr   error
+	shutdown atomic.Bool
+}
+
+func newFakeServer(runErr error) *fakeServer {
+	return &fakeServer{stop: make(chan struct{}), runErr: runErr}
+}
+
+func (f *fakeServer) Run() error {
+	if f.runErr != nil {
+		return f.runErr
+	}
+
+	<-f.stop
+	return nil
+}
+
+func (f *fakeServer) Shutdown(context.Context) error {
+	if f.shutdown.CompareAndSwap(false, true) {
+		close(f.stop)
+	}
+
+	return nil
+}
+
+func TestManager_RunStopsOnContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := newFakeServer(nil)
+	workerStopped := make(chan struct{})
+
+	m := NewManager(testLogger, time.Second)
+	m.AddServer("fake", srv)
+	m.AddWorker("worker", func(ctx context.Context) error {
+		<-ctx.Done()
+		close(workerStopped)
+		return ctx.Err()
+	})
+
+	go cancel()
+
+	err := m.Run(ctx)
+	assert.NoError(t, err)
+	assert.True(t, srv.shutdown.Load())
+	<-workerStopped
+}
+
+func TestManager_RunPropagatesFirstError(t *testing.T) {
+	failure := errors.New("listen failed")
+	healthy := newFakeServer(nil)
+
+	m := NewManager(testLogger, time.Second)
+	m.AddServer("healthy", healthy)
+	m.AddServer("broken", newFakeServer(failure))
+
+	err := m.Run(context.Background())
+	assert.ErrorIs(t, err, failure)
+	assert.True(t, healthy.shutdown.Load())
+}
+
+func TestManager_RunShutdownDeadline(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	m := NewManager(testLogger, 10*time.Millisecond)
+	m.AddWorker("stuck", func(context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	err := m.Run(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
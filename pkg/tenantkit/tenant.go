@@ -0,0 +1,37 @@
+package tenantkit
+
+import (
+	"context"
+
+	"github.com/wasay-usmani/go-boilerplate/pkg/errorx"
+)
+
+// Defaults of the tenant request header and JWT claim
+const (
+	Header = "X-Tenant-Id"
+	Claim  = "tenant_id"
+)
+
+type tenantCtxKey struct{}
+
+// WithTenant returns a copy of ctx carrying the tenant the request acts for.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantCtxKey{}, tenantID)
+}
+
+// FromContext returns the tenant carried by ctx, see WithTenant.
+func FromContext(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(tenantCtxKey{}).(string)
+	return tenantID, ok && tenantID != ""
+}
+
+// Require returns the tenant carried by ctx, an errorx.Forbidden when there is none,
+// so tenant scoped data is never read or written without a tenant.
+func Require(ctx context.Context) (string, error) {
+	tenantID, ok := FromContext(ctx)
+	if !ok {
+		return "", errorx.New(errorx.Forbidden, "tenant is required")
+	}
+
+	return tenantID, nil
+}
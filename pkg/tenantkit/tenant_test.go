@@ -0,0 +1,22 @@
+package tenantkit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasay-usmani/go-boilerplate/pkg/errorx"
+)
+
+func TestRequire(t *testing.T) {
+	tenantID, err := Require(WithTenant(context.Background(), "acme"))
+	require.NoError(t, err)
+	assert.Equal(t, "acme", tenantID)
+
+	_, err = Require(context.Background())
+	assert.True(t, errorx.Is(err, errorx.Forbidden))
+
+	_, err = Require(WithTenant(context.Background(), ""))
+	assert.True(t, errorx.Is(err, errorx.Forbidden))
+}
@@ -19,11 +19,14 @@ OUTPUT_DIR="$3"
 SERVICE_NAME_TITLE=$(echo "$SERVICE_NAME" | sed 's/-\([a-z]\)/\U\1/g' | sed 's/^\([a-z]\)/\U\1/')
 SERVICE_NAME_CAMEL=$(echo "$SERVICE_NAME" | sed 's/-\([a-z]\)/\U\1/g')
 SERVICE_NAME_PKG=$(echo "$SERVICE_NAME" | tr '[:upper:]' '[:lower:]' | sed 's/-//g' | sed 's/_//g')
+# Database, schema and user names, which hyphens would require to be quoted everywhere
+SERVICE_NAME_SNAKE=$(echo "$SERVICE_NAME" | tr '[:upper:]' '[:lower:]' | sed 's/-/_/g')
 
 echo "Creating microservice: $SERVICE_NAME"
 echo "Module path: $MODULE_PATH"
 echo "Output directory: $OUTPUT_DIR"
 echo "Package name: $SERVICE_NAME_PKG"
+echo "Database name: $SERVICE_NAME_SNAKE"
 echo ""
 
 # Function to process a template file
@@ -44,6 +47,7 @@ process_template() {
         -e "s/{{\.ServiceNameTitle}}/$SERVICE_NAME_TITLE/g" \
         -e "s/{{\.ServiceNameCamel}}/$SERVICE_NAME_CAMEL/g" \
         -e "s/{{\.ServiceNamePkg}}/$SERVICE_NAME_PKG/g" \
+        -e "s/{{\.ServiceNameSnake}}/$SERVICE_NAME_SNAKE/g" \
         -e "s|{{\.ModulePath}}|$MODULE_PATH|g" \
         "$template_file" > "$output_file"
     
@@ -59,12 +63,21 @@ declare -A templates=(
     ["templates/internal/app/service/qrys.go.tmpl"]="$OUTPUT_DIR/internal/$SERVICE_NAME/app/$SERVICE_NAME/qrys.go"
     ["templates/internal/config/config.go.tmpl"]="$OUTPUT_DIR/internal/$SERVICE_NAME/config/config.go"
     ["templates/internal/config/config.toml.tmpl"]="$OUTPUT_DIR/internal/$SERVICE_NAME/config/config.toml"
+    ["templates/internal/migrations/schema/schema.go.tmpl"]="$OUTPUT_DIR/internal/$SERVICE_NAME/migrations/schema/schema.go"
+    ["templates/internal/migrations/schema/migrations/0001_create_schema.sql.tmpl"]="$OUTPUT_DIR/internal/$SERVICE_NAME/migrations/schema/migrations/0001_create_schema.sql"
+    ["templates/internal/migrations/app/app.go.tmpl"]="$OUTPUT_DIR/internal/$SERVICE_NAME/migrations/app/app.go"
+    ["templates/internal/migrations/app/migrations/0001_init.sql.tmpl"]="$OUTPUT_DIR/internal/$SERVICE_NAME/migrations/app/migrations/0001_init.sql"
+    ["templates/internal/migrations/app/migrations/mysql/0002_create_outbox.sql.tmpl"]="$OUTPUT_DIR/internal/$SERVICE_NAME/migrations/app/migrations/mysql/0002_create_outbox.sql"
+    ["templates/internal/migrations/app/migrations/postgres/0002_create_outbox.sql.tmpl"]="$OUTPUT_DIR/internal/$SERVICE_NAME/migrations/app/migrations/postgres/0002_create_outbox.sql"
     ["templates/internal/repository/module.go.tmpl"]="$OUTPUT_DIR/internal/$SERVICE_NAME/repository/module.go"
+    ["templates/internal/repository/convertors/convertors.go.tmpl"]="$OUTPUT_DIR/internal/$SERVICE_NAME/repository/convertors/convertors.go"
+    ["templates/internal/repository/repotest/repotest.go.tmpl"]="$OUTPUT_DIR/internal/$SERVICE_NAME/repository/repotest/repotest.go"
     ["templates/internal/server/http/api.go.tmpl"]="$OUTPUT_DIR/internal/$SERVICE_NAME/server/http/api.go"
     ["templates/internal/server/http/routes.go.tmpl"]="$OUTPUT_DIR/internal/$SERVICE_NAME/server/http/routes.go"
     ["templates/internal/server/http/health.go.tmpl"]="$OUTPUT_DIR/internal/$SERVICE_NAME/server/http/health.go"
     ["templates/internal/server/rpc/rpc.go.tmpl"]="$OUTPUT_DIR/internal/$SERVICE_NAME/server/rpc/rpc.go"
     ["templates/resources/Dockerfile.tmpl"]="$OUTPUT_DIR/resources/$SERVICE_NAME/Dockerfile"
+    ["templates/resources/sqlboiler.toml.tmpl"]="$OUTPUT_DIR/resources/$SERVICE_NAME/sqlboiler.toml"
 )
 
 # Process each template